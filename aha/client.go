@@ -0,0 +1,109 @@
+package aha
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/duglin/integration/internal/httpclient"
+)
+
+type clientConfig struct {
+	httpclient.Config
+
+	pageSize int
+	fetchers int
+}
+
+// ClientOption configures how an AhaClient talks to the server.
+// See NewAhaClientE.
+type ClientOption func(*clientConfig)
+
+// WithHTTPClient uses the supplied http.Client as is. All other HTTP
+// options are ignored when this one is present.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(cfg *clientConfig) { cfg.HTTPClient = client }
+}
+
+// WithTransport uses the supplied RoundTripper instead of building one.
+// The CA, proxy and TLS options are ignored when this one is present.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(cfg *clientConfig) { cfg.Transport = rt }
+}
+
+// WithCABundle adds the PEM encoded certificates to the set of trusted
+// root CAs (in addition to the system ones).
+func WithCABundle(pem []byte) ClientOption {
+	return func(cfg *clientConfig) { cfg.AddCABundle(pem) }
+}
+
+// WithCAFile is the same as WithCABundle but reads the PEM from a file.
+func WithCAFile(file string) ClientOption {
+	return func(cfg *clientConfig) { cfg.AddCAFile(file) }
+}
+
+// WithProxy sends all requests through the proxy at proxyURL. By default
+// the standard HTTPS_PROXY/NO_PROXY env vars are used.
+func WithProxy(proxyURL string) ClientOption {
+	return func(cfg *clientConfig) { cfg.SetProxy(proxyURL) }
+}
+
+// WithTimeout sets the overall timeout for each HTTP request.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(cfg *clientConfig) { cfg.Timeout = timeout }
+}
+
+// WithInsecureSkipVerify turns off TLS certificate verification. Only
+// use this for testing against servers with self-signed certs.
+func WithInsecureSkipVerify() ClientOption {
+	return func(cfg *clientConfig) { cfg.Insecure = true }
+}
+
+// WithPageSize sets the number of records asked for in each page of a
+// list, 0 means use Aha's default
+func WithPageSize(size int) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.pageSize = size
+	}
+}
+
+// WithConcurrentPages lets GetAll fetch up to 'n' pages at the same time
+// once the first page has said how many there are
+func WithConcurrentPages(n int) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.fetchers = n
+	}
+}
+
+func newClientConfig(opts []ClientOption) *clientConfig {
+	cfg := &clientConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithContext returns a copy of the client that uses ctx for every
+// request. Anything retrieved through the copy inherits ctx too:
+//
+//	product, err := ac.WithContext(ctx).GetProduct("PROD")
+//	features, err := product.GetFeatures() // stops when ctx is done
+func (ac *AhaClient) WithContext(ctx context.Context) *AhaClient {
+	newClient := *ac
+	newClient.ctx = ctx
+	return &newClient
+}
+
+func (ac *AhaClient) context() context.Context {
+	if ac.ctx != nil {
+		return ac.ctx
+	}
+	return context.Background()
+}
+
+func (ac *AhaClient) httpClient() *http.Client {
+	if ac.HTTPClient != nil {
+		return ac.HTTPClient
+	}
+	return httpclient.Default
+}
@@ -0,0 +1,100 @@
+package aha
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Use these with errors.Is() to check what kind of error Aha() returned:
+//
+//	if _, err := ac.Aha("GET", url, ""); errors.Is(err, aha.ErrNotFound) {
+var (
+	ErrUnauthorized = errors.New("aha: unauthorized")
+	ErrForbidden    = errors.New("aha: forbidden")
+	ErrNotFound     = errors.New("aha: not found")
+	ErrValidation   = errors.New("aha: validation failed")
+	ErrRateLimited  = errors.New("aha: rate limited")
+	ErrServer       = errors.New("aha: server error")
+)
+
+// FieldError is one entry of the "errors" list of an Aha error response
+type FieldError struct {
+	Attribute string
+	Message   string
+}
+
+// APIError is returned by Aha() for any non-2xx response
+type APIError struct {
+	StatusCode int
+	Message    string
+	Errors     []FieldError
+
+	Method  string
+	URL     string
+	ReqBody string
+	Body    []byte // raw response body
+}
+
+func newAPIError(method, url, reqBody string, statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Method:     method,
+		URL:        url,
+		ReqBody:    reqBody,
+		Body:       body,
+	}
+
+	// Aha isn't consistent, we've seen all of these:
+	//   {"error":"Not Found"}
+	//   {"errors":{"message":"Validation failed: ..."}}
+	//   {"errors":[{"attribute":"name","message":"can't be blank"}]}
+	ahaErr := struct {
+		Error  string
+		Errors json.RawMessage
+	}{}
+	if err := json.Unmarshal(body, &ahaErr); err != nil {
+		return apiErr
+	}
+	apiErr.Message = ahaErr.Error
+
+	single := FieldError{}
+	if err := json.Unmarshal(ahaErr.Errors, &apiErr.Errors); err != nil {
+		if err = json.Unmarshal(ahaErr.Errors, &single); err == nil {
+			if apiErr.Message == "" {
+				apiErr.Message = single.Message
+			}
+			if single.Attribute != "" {
+				apiErr.Errors = []FieldError{single}
+			}
+		}
+	}
+	if apiErr.Message == "" && len(apiErr.Errors) > 0 {
+		apiErr.Message = apiErr.Errors[0].Message
+	}
+
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Aha: Error %s: %d %s\nReq Body: %s\n", e.URL,
+		e.StatusCode, string(e.Body), e.ReqBody)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == 401
+	case ErrForbidden:
+		return e.StatusCode == 403
+	case ErrNotFound:
+		return e.StatusCode == 404
+	case ErrValidation:
+		return e.StatusCode == 422
+	case ErrRateLimited:
+		return e.StatusCode == 429
+	case ErrServer:
+		return e.StatusCode/100 == 5
+	}
+	return false
+}
@@ -2,16 +2,17 @@ package aha
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"net/url"
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 )
 
 type AhaResponse struct {
@@ -21,6 +22,10 @@ type AhaResponse struct {
 }
 
 func (ac *AhaClient) Aha(method string, url string, body string) (*AhaResponse, error) {
+	return ac.AhaContext(ac.context(), method, url, body)
+}
+
+func (ac *AhaClient) AhaContext(ctx context.Context, method string, url string, body string) (*AhaResponse, error) {
 	// defer fmt.Printf("\n")
 	// fmt.Printf("%s %s", method, url)
 	ahaResponse := AhaResponse{}
@@ -33,7 +38,8 @@ func (ac *AhaClient) Aha(method string, url string, body string) (*AhaResponse,
 	if body != "" {
 		buf = []byte(body)
 	}
-	req, err := http.NewRequest(method, url, bytes.NewReader(buf))
+	req, err := http.NewRequestWithContext(ctx, method, url,
+		bytes.NewReader(buf))
 	if err != nil {
 		return nil, err
 	}
@@ -41,11 +47,9 @@ func (ac *AhaClient) Aha(method string, url string, body string) (*AhaResponse,
 	req.Header.Add("Authorization", "Bearer "+ac.Token)
 	req.Header.Add("Content-Type", "application/json")
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
+	client := ac.httpClient()
 
-	res, err := (&http.Client{Transport: tr}).Do(req)
+	res, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -55,6 +59,15 @@ func (ac *AhaClient) Aha(method string, url string, body string) (*AhaResponse,
 	ahaResponse.StatusCode = res.StatusCode
 	// fmt.Printf(" - %d", res.StatusCode)
 
+	// fmt.Printf("\n\n\nGET: %s\n%s\n", url, string(buf))
+	if res.StatusCode/100 != 2 {
+		// fmt.Printf("Aha Error:\n--> %s %s\n--> %s\n", method, url, body)
+		// fmt.Printf("%d %s\n", res.StatusCode, string(buf))
+		ahaResponse.Body = string(buf)
+		return &ahaResponse,
+			newAPIError(method, url, body, res.StatusCode, buf)
+	}
+
 	if len(buf) > 0 {
 		rawMap := map[string]json.RawMessage{} // interface{}{}
 		err = json.Unmarshal([]byte(buf), &rawMap)
@@ -79,53 +92,31 @@ func (ac *AhaClient) Aha(method string, url string, body string) (*AhaResponse,
 		}
 	}
 
-	// fmt.Printf("\n\n\nGET: %s\n%s\n", url, string(buf))
-	if res.StatusCode/100 != 2 {
-		// fmt.Printf("Aha Error:\n--> %s %s\n--> %s\n", method, url, body)
-		// fmt.Printf("%d %s\n", res.StatusCode, string(buf))
-		return &ahaResponse,
-			fmt.Errorf("Aha: Error %s: %d %s\nReq Body: %s\n", url,
-				res.StatusCode, string(buf), body)
-	}
-
 	return &ahaResponse, nil
 }
 
 func (ac *AhaClient) GetAll(daURL string, daItem interface{}) (interface{}, error) {
-	size := 0 // unlimited
+	return ac.GetAllContext(ac.context(), daURL, daItem)
+}
 
-	URL, err := url.Parse(daURL)
+// GetAllContext stops fetching pages as soon as ctx is done
+func (ac *AhaClient) GetAllContext(ctx context.Context, daURL string, daItem interface{}) (interface{}, error) {
+	pager, err := ac.newPager(ctx, daURL)
 	if err != nil {
 		return nil, err
 	}
-	if len(URL.RawQuery) == 0 {
-		daURL += "?"
-	}
 
-	if size != 0 {
-		daURL = fmt.Sprintf("%s&size=%d", daURL, size)
-	}
-
-	oldURL := daURL
 	daType := reflect.TypeOf(daItem)
 	result := reflect.MakeSlice(daType, 0, 0)
 
-	for daURL != "" {
-		var res *AhaResponse
-		var err error
-		if res, err = ac.Aha("GET", daURL, ""); err != nil {
-			return nil, err
-		}
-
+	for !pager.Done() {
 		// Create a pointer Value to a slice, JSON Unmarshal needs a ptr
 		itemsPtr := reflect.New(daType)
 
 		// Create an empty slice Value and make our pointer reference it
 		itemsPtr.Elem().Set(reflect.MakeSlice(daType, 0, 0))
 
-		err = json.Unmarshal([]byte(res.Body), itemsPtr.Interface())
-		if err != nil {
-			// fmt.Printf("%#v\n", res.Body)
+		if err := pager.NextPage(itemsPtr.Interface()); err != nil {
 			return nil, err
 		}
 
@@ -133,19 +124,75 @@ func (ac *AhaClient) GetAll(daURL string, daItem interface{}) (interface{}, erro
 		// then append it to the result set
 		result = reflect.AppendSlice(result, itemsPtr.Elem())
 
-		if res.PageInfo.Current_Page != res.PageInfo.Total_Pages {
-			daURL = fmt.Sprintf("%s&page=%d", oldURL, res.PageInfo.Current_Page+1)
-			if size > 0 {
-				daURL += fmt.Sprintf("&per_page=%d", size)
+		// Now that we know how many pages there are, get the rest of them
+		// in parallel if we're allowed to
+		if ac.Fetchers > 1 && !pager.Done() {
+			pages, err := ac.getPages(ctx, pager, daType)
+			if err != nil {
+				return nil, err
 			}
-		} else {
-			daURL = ""
+			for _, page := range pages {
+				result = reflect.AppendSlice(result, page)
+			}
+			break
 		}
 	}
 
 	return result.Interface(), nil
 }
 
+// getPages fetches pages 2 through Total_Pages, at most Fetchers at a
+// time. The pages are returned in order. The first error cancels the rest.
+func (ac *AhaClient) getPages(ctx context.Context, pager *Pager, daType reflect.Type) ([]reflect.Value, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	total := pager.PageInfo.Total_Pages
+	pages := make([]reflect.Value, total+1)
+	errs := make([]error, total+1)
+	sem := make(chan struct{}, ac.Fetchers)
+	wg := sync.WaitGroup{}
+
+	for num := 2; num <= total; num++ {
+		wg.Add(1)
+		go func(num int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := ctx.Err(); err != nil {
+				errs[num] = err
+				return
+			}
+
+			res, err := ac.AhaContext(ctx, "GET", pager.pageURL(num), "")
+			if err == nil {
+				itemsPtr := reflect.New(daType)
+				itemsPtr.Elem().Set(reflect.MakeSlice(daType, 0, 0))
+				err = json.Unmarshal([]byte(res.Body), itemsPtr.Interface())
+				pages[num] = itemsPtr.Elem()
+			}
+			if err != nil {
+				errs[num] = err
+				cancel()
+			}
+		}(num)
+	}
+	wg.Wait()
+
+	// Report the first real error, not the ones caused by cancel()
+	var firstErr error
+	for _, err := range errs {
+		if err != nil && (firstErr == nil || errors.Is(firstErr, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return pages[2:], nil
+}
+
 func SprintfJSON(obj interface{}) string {
 	res, err := json.MarshalIndent(obj, "", "  ")
 	if err != nil {
@@ -155,7 +202,11 @@ func SprintfJSON(obj interface{}) string {
 }
 
 func (product *Product) GetFeatures() ([]*Feature, error) {
-	items, err := product.GetAll(product.AhaClient.URL+"/api/v1/products/"+product.ID+"/features?fields=*",
+	return product.GetFeaturesContext(product.context())
+}
+
+func (product *Product) GetFeaturesContext(ctx context.Context) ([]*Feature, error) {
+	items, err := product.GetAllContext(ctx, product.AhaClient.URL+"/api/v1/products/"+product.ID+"/features?fields=*",
 		[]*Feature{})
 	if err != nil {
 		return nil, err
@@ -174,7 +225,7 @@ func (product *Product) GetFeatures() ([]*Feature, error) {
 func (product *Product) GetFeaturesByReleaseName(name string) ([]*Feature, error) {
 	rel, err := product.GetReleaseByName(name)
 	if err != nil {
-		return nil, fmt.Errorf("Can't find Aha release %q: %s", name, err)
+		return nil, fmt.Errorf("Can't find Aha release %q: %w", name, err)
 	}
 	if rel == nil {
 		return nil, fmt.Errorf("Can't find Aha release %q", name)
@@ -218,7 +269,7 @@ func (product *Product) GetFeatureByID(id string) (*Feature, error) {
 func (product *Product) CreateFeature(title string, relName string, desc string) (*Feature, error) {
 	rel, err := product.GetReleaseByName(relName)
 	if err != nil {
-		return nil, fmt.Errorf("Can't find Aha release %q: %s", relName, err)
+		return nil, fmt.Errorf("Can't find Aha release %q: %w", relName, err)
 	}
 	if rel == nil {
 		return nil, fmt.Errorf("Can't find Aha release %q", relName)
@@ -240,7 +291,7 @@ func (product *Product) CreateFeature(title string, relName string, desc string)
 		product.AhaClient.URL+"/api/v1/releases/"+rel.Reference_Num+
 			"/features", data)
 	if err != nil {
-		return nil, fmt.Errorf("Error creating Aha feature: %s", err)
+		return nil, fmt.Errorf("Error creating Aha feature: %w", err)
 	}
 
 	f := struct{ Feature Feature }{}
@@ -418,17 +469,13 @@ func (feature *Feature) Refresh() error {
 }
 
 func (feature *Feature) Delete() (bool, error) {
-	res, err := feature.Aha("DELETE",
+	_, err := feature.Aha("DELETE",
 		feature.AhaClient.URL+"/api/v1/features/"+feature.Reference_Num, "")
-	if err == nil {
-		return true, nil
-	}
-
-	if res.StatusCode == 404 {
+	if err == nil || errors.Is(err, ErrNotFound) {
 		return true, nil
 	}
 
-	return false, fmt.Errorf("Error deleting feature %q: %s",
+	return false, fmt.Errorf("Error deleting feature %q: %w",
 		feature.Reference_Num, err)
 }
 
@@ -440,7 +487,7 @@ func (feature *Feature) SetReleaseByID(id string) error {
 	_, err := feature.Aha("PUT",
 		feature.AhaClient.URL+"/api/v1/features/"+feature.Reference_Num, body)
 	if err != nil {
-		err = fmt.Errorf("Error moving Feature %q to release %q",
+		err = fmt.Errorf("Error moving Feature %q to release %q: %w",
 			feature.Reference_Num, id, err)
 	}
 	return err
@@ -449,7 +496,7 @@ func (feature *Feature) SetReleaseByID(id string) error {
 func (feature *Feature) SetReleaseByName(name string) error {
 	rel, err := feature.Product.GetReleaseByName(name)
 	if err != nil {
-		return fmt.Errorf("Can't find Aha release %q: %s", name, err)
+		return fmt.Errorf("Can't find Aha release %q: %w", name, err)
 	}
 	if rel == nil {
 		return fmt.Errorf("Can't find Aha release %q", name)
@@ -481,8 +528,8 @@ func (feature *Feature) SetGitURL(url string) error {
 	_, err := feature.Aha("PUT",
 		feature.AhaClient.URL+"/api/v1/features/"+feature.Reference_Num, body)
 	if err != nil {
-		err = fmt.Errorf("Error setting Aha feature(%s) GitURL: %s",
-			feature.Reference_Num, url)
+		err = fmt.Errorf("Error setting Aha feature(%s) GitURL: %s: %w",
+			feature.Reference_Num, url, err)
 	}
 
 	return err
@@ -497,7 +544,7 @@ func (feature *Feature) SetName(name string) error {
 	_, err := feature.Aha("PUT",
 		feature.AhaClient.URL+"/api/v1/features/"+feature.Reference_Num, body)
 	if err != nil {
-		err = fmt.Errorf("Error updating Aha feature(%s) title: %s -> %s",
+		err = fmt.Errorf("Error updating Aha feature(%s) title: %s -> %w",
 			feature.Reference_Num, name, err)
 	}
 
@@ -513,13 +560,28 @@ func (feature *Feature) SetStatus(status string) error {
 	_, err := feature.Aha("PUT",
 		feature.AhaClient.URL+"/api/v1/features/"+feature.Reference_Num, body)
 	if err != nil {
-		err = fmt.Errorf("Error updating Aha feature(%s) status: %s -> %s",
+		err = fmt.Errorf("Error updating Aha feature(%s) status: %s -> %w",
 			feature.Reference_Num, status, err)
 	}
 
 	return err
 }
 
+func (feature *Feature) SetDescription(desc string) error {
+	buf, _ := json.Marshal(desc)
+
+	body := fmt.Sprintf(`{"feature":{"description":%s}}`, string(buf))
+
+	_, err := feature.Aha("PUT",
+		feature.AhaClient.URL+"/api/v1/features/"+feature.Reference_Num, body)
+	if err != nil {
+		err = fmt.Errorf("Error updating Aha feature(%s) description: %w",
+			feature.Reference_Num, err)
+	}
+
+	return err
+}
+
 func (feature *Feature) SetDueDate(date string) error {
 	buf, _ := json.Marshal(date)
 	date = string(buf)
@@ -529,7 +591,7 @@ func (feature *Feature) SetDueDate(date string) error {
 	_, err := feature.Aha("PUT",
 		feature.AhaClient.URL+"/api/v1/features/"+feature.Reference_Num, body)
 	if err != nil {
-		err = fmt.Errorf("Error updating Aha feature(%s) end_date: %s -> %s",
+		err = fmt.Errorf("Error updating Aha feature(%s) end_date: %s -> %w",
 			feature.Reference_Num, date, err)
 	}
 
@@ -559,7 +621,7 @@ func (feature *Feature) AddTag(tag string) error {
 	res, err := feature.Aha("PUT",
 		feature.AhaClient.URL+"/api/v1/features/"+feature.Reference_Num, body)
 	if err != nil {
-		return fmt.Errorf("Error adding tag %q: %s", tag, err)
+		return fmt.Errorf("Error adding tag %q: %w", tag, err)
 	}
 
 	f := struct{ Feature Feature }{}
@@ -593,7 +655,7 @@ func (feature *Feature) RemoveTag(tag string) error {
 	res, err := feature.Aha("PUT",
 		feature.AhaClient.URL+"/api/v1/features/"+feature.Reference_Num, body)
 	if err != nil {
-		return fmt.Errorf("Error removing tag %q: %s", tag, err)
+		return fmt.Errorf("Error removing tag %q: %w", tag, err)
 	}
 
 	f := struct{ Feature Feature }{}
@@ -1169,8 +1231,8 @@ func (feature *Feature) CustomField(name, action, value string) (string, error)
 		res, err := feature.Aha("PUT",
 			feature.AhaClient.URL+"/api/v1/features/"+feature.Reference_Num, body)
 		if err != nil {
-			return "", fmt.Errorf("Error setting feature(%s) field: %q to %q. %s",
-				feature.Reference_Num, name, value, res.StatusCode, err.Error())
+			return "", fmt.Errorf("Error setting feature(%s) field: %q to %q: %w",
+				feature.Reference_Num, name, value, err)
 		}
 		f := struct{ Feature Feature }{}
 		err = json.Unmarshal([]byte(res.Body), &f)
@@ -1488,8 +1550,8 @@ func (feature *Feature) AddCustomFieldValue(name, value string) error {
 		res, err := feature.Aha("PUT",
 			feature.AhaClient.URL+"/api/v1/features/"+feature.Reference_Num, body)
 		if err != nil {
-			return fmt.Errorf("Error setting feature(%s) field: %q to %q. %s",
-				feature.Reference_Num, name, value, res.StatusCode, err.Error())
+			return fmt.Errorf("Error setting feature(%s) field: %q to %q: %w",
+				feature.Reference_Num, name, value, err)
 		}
 		f := struct{ Feature Feature }{}
 		err = json.Unmarshal([]byte(res.Body), &f)
@@ -1681,8 +1743,8 @@ func (feature *Feature) RemoveCustomFieldValue(name, value string) error {
 		res, err := feature.Aha("PUT",
 			feature.AhaClient.URL+"/api/v1/features/"+feature.Reference_Num, body)
 		if err != nil {
-			return fmt.Errorf("Error setting feature(%s) field: %q to %q. %s",
-				feature.Reference_Num, name, value, res.StatusCode, err.Error())
+			return fmt.Errorf("Error setting feature(%s) field: %q to %q: %w",
+				feature.Reference_Num, name, value, err)
 		}
 		f := struct{ Feature Feature }{}
 		err = json.Unmarshal([]byte(res.Body), &f)
@@ -1731,14 +1793,58 @@ func (ac *AhaClient) GetProduct(id string) (*Product, error) {
 }
 
 func (ac *AhaClient) DeleteFeature(id string) (bool, error) {
-	res, err := ac.Aha("DELETE", ac.URL+"/api/v1/features/"+id, "")
-	if err == nil {
+	_, err := ac.Aha("DELETE", ac.URL+"/api/v1/features/"+id, "")
+	if err == nil || errors.Is(err, ErrNotFound) {
 		return true, nil
 	}
 
-	if res.StatusCode == 404 {
-		return true, nil
+	return false, fmt.Errorf("Error deleting feature %q: %w", id, err)
+}
+
+// GetFeature fetches a feature (by ID or reference number) along with
+// the Product it belongs to
+func (ac *AhaClient) GetFeature(id string) (*Feature, error) {
+	res, err := ac.Aha("GET", ac.URL+"/api/v1/features/"+id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	f := struct{ Feature Feature }{}
+	err = json.Unmarshal([]byte(res.Body), &f)
+	if err != nil {
+		return nil, err
+	}
+	f.Feature.AhaClient = ac
+
+	if f.Feature.Product_ID != "" {
+		if f.Feature.Product, err = ac.GetProduct(f.Feature.Product_ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &f.Feature, nil
+}
+
+// GetRelease fetches a release (by ID or reference number) along with
+// the Product it belongs to
+func (ac *AhaClient) GetRelease(id string) (*Release, error) {
+	res, err := ac.Aha("GET", ac.URL+"/api/v1/releases/"+id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	r := struct{ Release Release }{}
+	err = json.Unmarshal([]byte(res.Body), &r)
+	if err != nil {
+		return nil, err
+	}
+	r.Release.AhaClient = ac
+
+	if r.Release.Product_ID != "" {
+		if r.Release.Product, err = ac.GetProduct(r.Release.Product_ID); err != nil {
+			return nil, err
+		}
 	}
 
-	return false, fmt.Errorf("Error deleting feature %q: %s", id, err)
+	return &r.Release, nil
 }
@@ -0,0 +1,165 @@
+package aha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// Pager walks a paginated list resource one page at a time. Nothing is
+// fetched until NextPage is called. After the first page PageInfo has the
+// total number of records and pages.
+type Pager struct {
+	*AhaClient
+
+	PageInfo Pagination
+
+	ctx     context.Context
+	baseURL string
+	page    int // last page fetched, 0 if none yet
+	done    bool
+}
+
+func (ac *AhaClient) NewPager(daURL string) (*Pager, error) {
+	return ac.newPager(ac.context(), daURL)
+}
+
+func (ac *AhaClient) newPager(ctx context.Context, daURL string) (*Pager, error) {
+	URL, err := url.Parse(daURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(URL.RawQuery) == 0 {
+		daURL += "?"
+	}
+	if ac.PageSize > 0 {
+		daURL = fmt.Sprintf("%s&per_page=%d", daURL, ac.PageSize)
+	}
+
+	return &Pager{
+		AhaClient: ac,
+		ctx:       ctx,
+		baseURL:   daURL,
+	}, nil
+}
+
+func (p *Pager) Done() bool {
+	return p.done
+}
+
+// Total is the number of records in all pages, 0 until the first page
+// has been fetched
+func (p *Pager) Total() int {
+	return p.PageInfo.Total_Records
+}
+
+func (p *Pager) pageURL(page int) string {
+	if page <= 1 {
+		return p.baseURL
+	}
+	return fmt.Sprintf("%s&page=%d", p.baseURL, page)
+}
+
+// NextPage unmarshals the next page into 'items', a pointer to a slice
+func (p *Pager) NextPage(items interface{}) error {
+	if p.done {
+		return fmt.Errorf("No more pages")
+	}
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := p.AhaContext(p.ctx, "GET", p.pageURL(p.page+1), "")
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal([]byte(res.Body), items); err != nil {
+		return err
+	}
+
+	p.page++
+	p.PageInfo = res.PageInfo
+	if res.PageInfo.Current_Page >= res.PageInfo.Total_Pages {
+		p.done = true
+	}
+	return nil
+}
+
+// FeatureIter returns the features of a product one at a time, fetching
+// the next page only when it's needed. Just stop calling Next to stop
+// early:
+//
+//	it := product.FeaturesIter()
+//	for it.Next() {
+//		feature := it.Feature()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+type FeatureIter struct {
+	pager   *Pager
+	product *Product
+	page    []*Feature
+	index   int
+	feature *Feature
+	err     error
+}
+
+func (product *Product) FeaturesIter() *FeatureIter {
+	it := &FeatureIter{product: product}
+	it.pager, it.err = product.NewPager(product.AhaClient.URL +
+		"/api/v1/products/" + product.ID + "/features?fields=*")
+	return it
+}
+
+// fetch gets the next page, returns false if there isn't one
+func (it *FeatureIter) fetch() bool {
+	if it.err != nil || it.pager.Done() {
+		return false
+	}
+
+	page := []*Feature{}
+	if it.err = it.pager.NextPage(&page); it.err != nil {
+		return false
+	}
+	for _, f := range page {
+		f.AhaClient = it.product.AhaClient
+		f.Product = it.product
+	}
+	it.page, it.index = page, 0
+	return true
+}
+
+func (it *FeatureIter) Next() bool {
+	for it.index >= len(it.page) {
+		if !it.fetch() {
+			it.feature = nil
+			return false
+		}
+	}
+
+	it.feature = it.page[it.index]
+	it.index++
+	return true
+}
+
+// Feature is the feature found by the last call to Next
+func (it *FeatureIter) Feature() *Feature {
+	return it.feature
+}
+
+// Total is the number of features in the product, as reported by Aha.
+// The first page is fetched if it hasn't been yet.
+func (it *FeatureIter) Total() (int, error) {
+	if it.err == nil && it.pager.page == 0 {
+		it.fetch()
+	}
+	if it.err != nil {
+		return 0, it.err
+	}
+	return it.pager.Total(), nil
+}
+
+func (it *FeatureIter) Err() error {
+	return it.err
+}
@@ -1,19 +1,48 @@
 package aha
 
+import (
+	"context"
+	"net/http"
+)
+
 // https://www.aha.io/api
 
 type AhaClient struct {
 	URL    string
 	Token  string
 	Secret string // used to verify events are from Aha
+
+	HTTPClient *http.Client // nil means use a shared default client
+	PageSize   int          // records per page, 0 means Aha's default
+	Fetchers   int          // pages GetAll fetches at once, <= 1 is serial
+	ctx        context.Context
+}
+
+// NewAhaClient panics if one of the options is bad (e.g. an unreadable CA
+// file), use NewAhaClientE to get the error instead
+func NewAhaClient(url string, token string, secret string, opts ...ClientOption) *AhaClient {
+	client, err := NewAhaClientE(url, token, secret, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return client
 }
 
-func NewAhaClient(url string, token string, secret string) *AhaClient {
+func NewAhaClientE(url string, token string, secret string, opts ...ClientOption) (*AhaClient, error) {
+	cfg := newClientConfig(opts)
+	client, err := cfg.NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
 	return &AhaClient{
 		URL:    url,
 		Token:  token,
 		Secret: secret,
-	}
+
+		HTTPClient: client,
+		PageSize:   cfg.pageSize,
+		Fetchers:   cfg.fetchers,
+	}, nil
 }
 
 type Pagination struct {
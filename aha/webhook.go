@@ -0,0 +1,264 @@
+package aha
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const maxWebhookBody = 10 * 1024 * 1024
+
+// VerifyEvent checks that a webhook delivery came from Aha. If the
+// X-Aha-Signature header is present it must be the hex HMAC-SHA256 of the
+// body using Secret, otherwise X-Aha-Verify-Token must match Secret.
+func (ac *AhaClient) VerifyEvent(req *http.Request, body []byte) bool {
+	if ac.Secret == "" {
+		return false
+	}
+
+	if sig := req.Header.Get("X-Aha-Signature"); sig != "" {
+		sig = strings.TrimPrefix(sig, "sha256=")
+		calc, err := hex.DecodeString(sig)
+		if err != nil {
+			return false
+		}
+
+		mac := hmac.New(sha256.New, []byte(ac.Secret))
+		mac.Write(body)
+		return hmac.Equal(calc, mac.Sum(nil))
+	}
+
+	token := req.Header.Get("X-Aha-Verify-Token")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(ac.Secret)) == 1
+}
+
+// FieldChange is one entry of Event.Audit.Changes
+type FieldChange struct {
+	Field string
+	Value interface{}
+}
+
+// Text returns the Value as a string, non-strings are JSON encoded
+func (fc *FieldChange) Text() string {
+	if str, ok := fc.Value.(string); ok {
+		return str
+	}
+	if fc.Value == nil {
+		return ""
+	}
+	buf, _ := json.Marshal(fc.Value)
+	return string(buf)
+}
+
+func (e *Event) SetAha(ac *AhaClient) {
+	if e != nil {
+		e.AhaClient = ac
+		if e.Audit.User != nil {
+			e.Audit.User.AhaClient = ac
+		}
+		for _, c := range e.Audit.Contributors {
+			if c.User != nil {
+				c.User.AhaClient = ac
+			}
+		}
+	}
+}
+
+// Type is the lowercase Auditable_Type, e.g. "feature", "release"
+func (e *Event) Type() string {
+	return strings.ToLower(e.Audit.Auditable_Type)
+}
+
+// Action is the lowercase Audit_Action, e.g. "create", "update", "destroy"
+func (e *Event) Action() string {
+	return strings.ToLower(e.Audit.Audit_Action)
+}
+
+func (e *Event) FieldChanges() []*FieldChange {
+	changes := []*FieldChange{}
+	for _, c := range e.Audit.Changes {
+		changes = append(changes, &FieldChange{
+			Field: c.Field_Name,
+			Value: c.Value,
+		})
+	}
+	return changes
+}
+
+// GetChange returns the change made to 'field' (case insensitive), if any
+func (e *Event) GetChange(field string) *FieldChange {
+	for _, c := range e.FieldChanges() {
+		if strings.EqualFold(c.Field, field) {
+			return c
+		}
+	}
+	return nil
+}
+
+// objectID returns the ID of the 'kind' object this event is about. It's
+// either the audited object itself or the one it's associated with (e.g.
+// the feature that a requirement belongs to).
+func (e *Event) objectID(kind string) string {
+	if strings.EqualFold(e.Audit.Auditable_Type, kind) {
+		return e.Audit.Auditable_ID
+	}
+	if strings.EqualFold(e.Audit.Associated_Type, kind) {
+		return e.Audit.Associated_ID
+	}
+	return ""
+}
+
+// GetFeature fetches the current state of the Feature that was changed
+func (e *Event) GetFeature() (*Feature, error) {
+	id := e.objectID("feature")
+	if id == "" {
+		return nil, fmt.Errorf("Event isn't about a feature: %s/%s",
+			e.Audit.Auditable_Type, e.Audit.Associated_Type)
+	}
+	return e.AhaClient.GetFeature(id)
+}
+
+// GetRelease fetches the current state of the Release that was changed
+func (e *Event) GetRelease() (*Release, error) {
+	id := e.objectID("release")
+	if id == "" {
+		return nil, fmt.Errorf("Event isn't about a release: %s/%s",
+			e.Audit.Auditable_Type, e.Audit.Associated_Type)
+	}
+	return e.AhaClient.GetRelease(id)
+}
+
+// WebhookRouter is an http.Handler for Aha activity webhooks. Each
+// verified delivery is decoded into an Event and passed to the callbacks
+// registered for its Auditable_Type and Audit_Action:
+//
+//	router := aha.NewWebhookRouter(ac)
+//	router.On("feature", "update", func(e *aha.Event) { ... })
+//	http.Handle("/aha", router)
+type WebhookRouter struct {
+	*AhaClient
+
+	SkipVerify bool // don't check the signatures, only for testing
+
+	handlers []webhookHandler
+	onError  func(req *http.Request, err error)
+}
+
+type webhookHandler struct {
+	kind   string
+	action string
+	fn     func(*Event) error
+}
+
+func NewWebhookRouter(ac *AhaClient) *WebhookRouter {
+	return &WebhookRouter{
+		AhaClient: ac,
+		onError: func(req *http.Request, err error) {
+			log.Printf("Aha Webhook: %s", err)
+		},
+	}
+}
+
+// OnError sets the func that's called for any error while processing a
+// delivery. By default they're just logged.
+func (wr *WebhookRouter) OnError(fn func(req *http.Request, err error)) {
+	wr.onError = fn
+}
+
+// On registers a callback for events of type 'kind' (e.g. "feature") with
+// action 'action' (e.g. "update"). "" for either one matches anything.
+func (wr *WebhookRouter) On(kind string, action string, fn func(*Event) error) {
+	wr.handlers = append(wr.handlers, webhookHandler{
+		kind:   strings.ToLower(kind),
+		action: strings.ToLower(action),
+		fn:     fn,
+	})
+}
+
+// OnFeature calls fn with the changed Feature, freshly fetched from Aha,
+// for every feature event except deletes
+func (wr *WebhookRouter) OnFeature(fn func(*Event, *Feature) error) {
+	wr.On("", "", func(e *Event) error {
+		if e.Action() == "destroy" || e.objectID("feature") == "" {
+			return nil
+		}
+		feature, err := e.GetFeature()
+		if err != nil {
+			return err
+		}
+		return fn(e, feature)
+	})
+}
+
+// OnRelease calls fn with the changed Release, freshly fetched from Aha,
+// for every release event except deletes
+func (wr *WebhookRouter) OnRelease(fn func(*Event, *Release) error) {
+	wr.On("", "", func(e *Event) error {
+		if e.Action() == "destroy" || e.objectID("release") == "" {
+			return nil
+		}
+		release, err := e.GetRelease()
+		if err != nil {
+			return err
+		}
+		return fn(e, release)
+	})
+}
+
+func (wr *WebhookRouter) reportError(req *http.Request, err error) {
+	if wr.onError != nil {
+		wr.onError(req, err)
+	}
+}
+
+func (wr *WebhookRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxWebhookBody))
+	if err != nil {
+		wr.reportError(req, fmt.Errorf("Error reading body: %s", err))
+		http.Error(w, "Error reading body", http.StatusBadRequest)
+		return
+	}
+
+	if !wr.SkipVerify && !wr.VerifyEvent(req, body) {
+		wr.reportError(req, fmt.Errorf("Invalid signature"))
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event := Event{}
+	if err = json.Unmarshal(body, &event); err != nil {
+		wr.reportError(req, fmt.Errorf("Error parsing event: %s", err))
+		http.Error(w, "Error parsing event", http.StatusBadRequest)
+		return
+	}
+	event.SetAha(wr.AhaClient)
+
+	for _, h := range wr.handlers {
+		if h.kind != "" && h.kind != event.Type() {
+			continue
+		}
+		if h.action != "" && h.action != event.Action() {
+			continue
+		}
+		if err = h.fn(&event); err != nil {
+			wr.reportError(req, fmt.Errorf("Error processing %s/%s event: %s",
+				event.Type(), event.Action(), err))
+			http.Error(w, "Error processing event", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
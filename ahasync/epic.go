@@ -0,0 +1,318 @@
+package ahasync
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/duglin/integration/aha"
+	"github.com/duglin/integration/github"
+	"github.com/duglin/integration/zenhub"
+)
+
+// The GitData label, on the epic issue, that links an Aha requirement to
+// its child issue. The value is "REF #NUM".
+const RequirementLabel = "Aha-Requirement"
+
+// The GitData label, on the epic issue, with the Aha Feature's reference
+// num. It lets us find an epic whose link back from Aha wasn't saved.
+const FeatureLabel = "Aha-Feature"
+
+// EpicMirror mirrors an Aha Feature and its Requirements into a ZenHub
+// epic (the Feature's issue) with one child issue per Requirement.
+// A missing epic is created in Repo, missing child issues are created in
+// the epic's repo.
+type EpicMirror struct {
+	*github.GitHubClient
+
+	Zen  *zenhub.ZenHubClient
+	Repo *github.Repository
+
+	DryRun bool      // just print what would be done
+	Out    io.Writer // where DryRun output goes, default is stdout
+}
+
+// Drift is a difference between a requirement and its child issue that
+// the mirror doesn't fix on its own
+type Drift struct {
+	Requirement string // Aha reference num
+	Issue       int
+	Field       string // "title", "status" or "removed"
+	Aha         string
+	GitHub      string
+}
+
+type EpicResult struct {
+	Feature string
+	Epic    int   // issue number of the epic
+	Created []int // newly created child issues
+	Drift   []*Drift
+}
+
+func NewEpicMirror(gh *github.GitHubClient, zc *zenhub.ZenHubClient, repo *github.Repository) *EpicMirror {
+	return &EpicMirror{
+		GitHubClient: gh,
+		Zen:          zc,
+		Repo:         repo,
+	}
+}
+
+func (m *EpicMirror) Mirror(feature *aha.Feature) (*EpicResult, error) {
+	result := &EpicResult{Feature: feature.Reference_Num}
+
+	epic, err := m.epicIssue(feature)
+	if err != nil {
+		return nil, err
+	}
+	if epic == nil { // DryRun
+		m.printf("%s: create epic issue %q in %s\n", feature.Reference_Num,
+			feature.Name, m.Repo.Full_Name)
+		for _, req := range feature.Requirements {
+			m.printf("%s: create task issue %q\n", req.Reference_Num, req.Name)
+		}
+		return result, nil
+	}
+	result.Epic = epic.Number
+
+	repo, err := epic.GetRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	zi, err := m.Zen.GetIssue(repo.ID, epic.Number)
+	if err != nil {
+		return nil, err
+	}
+	if !zi.Is_Epic {
+		m.printf("%s: convert #%d to an epic\n", feature.Reference_Num,
+			epic.Number)
+		if !m.DryRun {
+			if err = m.Zen.MakeEpic(repo.ID, epic.Number); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	data, err := epic.LoadData()
+	if err != nil {
+		return nil, err
+	}
+	tasks := loadTasks(data)
+	seen := map[string]bool{}
+
+	// A link is saved before its issue is added to the epic, so a task
+	// may be missing from the epic if a previous run failed in between
+	inEpic := map[int]bool{}
+	if zi.Is_Epic || !m.DryRun {
+		ze, err := m.Zen.GetEpic(repo.ID, epic.Number)
+		if err != nil {
+			return nil, err
+		}
+		for _, ei := range ze.Issues {
+			if ei.Repo_ID == repo.ID {
+				inEpic[ei.Issue_Number] = true
+			}
+		}
+	}
+
+	for _, req := range feature.Requirements {
+		seen[req.Reference_Num] = true
+
+		if num, ok := tasks[req.Reference_Num]; ok {
+			if !inEpic[num] {
+				m.printf("%s: add #%d to epic #%d\n", req.Reference_Num, num,
+					epic.Number)
+				if !m.DryRun {
+					err = m.Zen.AddTask(repo.ID, epic.Number, repo.ID, num)
+					if err != nil {
+						return result, err
+					}
+				}
+			}
+
+			drift, err := m.checkTask(repo, req, num)
+			if err != nil {
+				return nil, err
+			}
+			result.Drift = append(result.Drift, drift...)
+			continue
+		}
+
+		m.printf("%s: create task issue %q in epic #%d\n", req.Reference_Num,
+			req.Name, epic.Number)
+		if m.DryRun {
+			continue
+		}
+
+		task, err := repo.CreateIssue(req.Name, strings.TrimSpace(req.Description.Body))
+		if err != nil {
+			return nil, err
+		}
+		result.Created = append(result.Created, task.Number)
+
+		// Save the link right away so a rerun doesn't create it again, a
+		// rerun adds it to the epic if AddTask fails
+		link := fmt.Sprintf("%s #%d", req.Reference_Num, task.Number)
+		if err = epic.AddData(RequirementLabel, link); err != nil {
+			return result, err
+		}
+
+		if err = m.Zen.AddTask(repo.ID, epic.Number, repo.ID, task.Number); err != nil {
+			return result, err
+		}
+	}
+
+	for ref, num := range tasks {
+		if !seen[ref] {
+			result.Drift = append(result.Drift, &Drift{
+				Requirement: ref,
+				Issue:       num,
+				Field:       "removed",
+			})
+		}
+	}
+
+	for _, d := range result.Drift {
+		m.printf("%s: drift in #%d %s: aha=%q github=%q\n", d.Requirement,
+			d.Issue, d.Field, d.Aha, d.GitHub)
+	}
+
+	return result, nil
+}
+
+// epicIssue returns the Feature's issue, creating (and linking) it if
+// needed. In DryRun mode a missing issue is returned as nil. The issue is
+// created with a FeatureLabel so if linking it fails the next run finds it
+// rather than creating another one.
+func (m *EpicMirror) epicIssue(feature *aha.Feature) (*github.Issue, error) {
+	url, err := feature.GetGitURL()
+	if err != nil {
+		return nil, err
+	}
+
+	if url != "" {
+		org, repo, num, err := ParseIssueURL(url)
+		if err != nil {
+			return nil, err
+		}
+		return m.GetIssueParts(org, repo, num)
+	}
+
+	issue, err := m.findEpic(feature)
+	if err != nil {
+		return nil, err
+	}
+	if issue != nil {
+		m.printf("%s: link to existing epic #%d\n", feature.Reference_Num,
+			issue.Number)
+	}
+
+	if m.DryRun {
+		return issue, nil
+	}
+
+	if issue == nil {
+		md := &github.Metadata{Body: strings.TrimSpace(feature.Description.Body)}
+		if err = md.Set(FeatureLabel, feature.Reference_Num); err != nil {
+			return nil, err
+		}
+		body, err := md.Encode()
+		if err != nil {
+			return nil, err
+		}
+		if issue, err = m.Repo.CreateIssue(feature.Name, body); err != nil {
+			return nil, err
+		}
+	}
+	if err = feature.SetGitURL(issue.HTML_URL); err != nil {
+		return nil, err
+	}
+	return issue, nil
+}
+
+// findEpic returns the issue in Repo created for the Feature by a run that
+// didn't get to link it, or nil. The search finds the reference num in the
+// FeatureLabel data, new issues can take a moment to be searchable.
+func (m *EpicMirror) findEpic(feature *aha.Feature) (*github.Issue, error) {
+	issues, err := m.SearchIssues(fmt.Sprintf(`repo:%s is:issue in:body %q`,
+		m.Repo.Full_Name, feature.Reference_Num))
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range issues {
+		if issue.HasData(FeatureLabel, feature.Reference_Num) {
+			return issue, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *EpicMirror) checkTask(repo *github.Repository, req *aha.Requirement, num int) ([]*Drift, error) {
+	issue, err := m.GetIssueParts(repo.Owner.Login, repo.Name, num)
+	if err != nil {
+		return nil, err
+	}
+
+	drift := []*Drift{}
+	if strings.TrimSpace(issue.Title) != strings.TrimSpace(req.Name) {
+		drift = append(drift, &Drift{
+			Requirement: req.Reference_Num,
+			Issue:       num,
+			Field:       "title",
+			Aha:         req.Name,
+			GitHub:      issue.Title,
+		})
+	}
+
+	reqState := "open"
+	if req.Workflow_Status != nil && req.Workflow_Status.Complete {
+		reqState = "closed"
+	}
+	if issue.State != reqState {
+		status := ""
+		if req.Workflow_Status != nil {
+			status = req.Workflow_Status.Name
+		}
+		drift = append(drift, &Drift{
+			Requirement: req.Reference_Num,
+			Issue:       num,
+			Field:       "status",
+			Aha:         status,
+			GitHub:      issue.State,
+		})
+	}
+
+	return drift, nil
+}
+
+func (m *EpicMirror) printf(format string, args ...interface{}) {
+	if !m.DryRun {
+		return
+	}
+	out := m.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintf(out, format, args...)
+}
+
+// loadTasks returns the requirement ref -> issue number links
+func loadTasks(data *github.GitData) map[string]int {
+	tasks := map[string]int{}
+	for _, entry := range data.Data {
+		if entry[0] != RequirementLabel {
+			continue
+		}
+		// REF #NUM
+		parts := strings.Fields(entry[1])
+		if len(parts) != 2 || !strings.HasPrefix(parts[1], "#") {
+			continue
+		}
+		if num, err := strconv.Atoi(parts[1][1:]); err == nil {
+			tasks[parts[0]] = num
+		}
+	}
+	return tasks
+}
@@ -0,0 +1,486 @@
+package ahasync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/duglin/integration/aha"
+	"github.com/duglin/integration/github"
+)
+
+func NewSyncer(gh *github.GitHubClient, cfg Config) *Syncer {
+	return &Syncer{
+		GitHubClient: gh,
+		Config:       cfg,
+	}
+}
+
+// ParseIssueURL extracts the org, repo and number from either the HTML
+// (https://HOST/ORG/REPO/issues/NUM) or API (.../repos/ORG/REPO/issues/NUM)
+// URL of an issue or pull request
+func ParseIssueURL(url string) (string, string, int, error) {
+	url = strings.TrimSuffix(url, "/")
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+	}
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+
+	parts := strings.Split(url, "/")
+	for i := len(parts) - 2; i >= 2; i-- {
+		if parts[i] == "issues" || parts[i] == "pull" || parts[i] == "pulls" {
+			num, err := strconv.Atoi(parts[i+1])
+			if err != nil {
+				break
+			}
+			return parts[i-2], parts[i-1], num, nil
+		}
+	}
+
+	return "", "", 0, fmt.Errorf("Can't parse issue URL %q", url)
+}
+
+// GetIssue returns the GitHub issue linked to the feature, or nil if the
+// feature doesn't have a GitURL
+func (s *Syncer) GetIssue(feature *aha.Feature) (*github.Issue, error) {
+	url, err := feature.GetGitURL()
+	if err != nil || url == "" {
+		return nil, err
+	}
+
+	org, repo, num, err := ParseIssueURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetIssueParts(org, repo, num)
+}
+
+// SyncProduct syncs every feature in the product that has a GitURL. It
+// keeps going after an error, all errors are returned together.
+func (s *Syncer) SyncProduct(product *aha.Product) ([]*Result, error) {
+	features, err := product.GetFeatures()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*Result{}
+	errs := []string{}
+
+	for _, feature := range features {
+		result, err := s.SyncFeature(feature)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", feature.Reference_Num, err))
+			continue
+		}
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+
+	if len(errs) > 0 {
+		return results, fmt.Errorf("Error syncing %d feature(s):\n%s",
+			len(errs), strings.Join(errs, "\n"))
+	}
+	return results, nil
+}
+
+// SyncFeature syncs the feature with its linked issue. Returns nil if the
+// feature isn't linked to an issue.
+func (s *Syncer) SyncFeature(feature *aha.Feature) (*Result, error) {
+	issue, err := s.GetIssue(feature)
+	if err != nil || issue == nil {
+		return nil, err
+	}
+	return s.Sync(feature, issue)
+}
+
+func (s *Syncer) Sync(feature *aha.Feature, issue *github.Issue) (*Result, error) {
+	result := &Result{
+		Feature: feature.Reference_Num,
+		Issue:   issue.HTML_URL,
+	}
+
+	// The description is the issue body w/o its data, the sync state is
+	// kept wherever the client's DataStore keeps data
+	data := issue.GetGitData()
+	stored, err := issue.LoadData()
+	if err != nil {
+		return nil, err
+	}
+	state := loadState(stored)
+	oldState := saveState(state)
+
+	for _, field := range Fields {
+		dir := s.Config.Directions[field]
+		if dir == Skip {
+			continue
+		}
+
+		ahaVal := s.ahaValue(field, feature)
+		gitVal := s.gitValue(field, issue, data)
+
+		if ahaVal == gitVal {
+			state[field] = hash(ahaVal)
+			continue
+		}
+
+		target := s.target(dir, state[field], ahaVal, gitVal)
+		if target == "" {
+			result.Conflicts = append(result.Conflicts, &Conflict{
+				Field:  field,
+				Aha:    ahaVal,
+				GitHub: gitVal,
+			})
+			continue
+		}
+
+		change := &Change{Field: field, Target: target, From: gitVal, To: ahaVal}
+		if target == "aha" {
+			change.From, change.To = ahaVal, gitVal
+		}
+		result.Changes = append(result.Changes, change)
+
+		if s.Config.DryRun {
+			continue
+		}
+
+		if target == "github" {
+			err := s.setGitValue(field, issue, change.From, change.To)
+			if err != nil {
+				return result, err
+			}
+		} else {
+			err := s.setAhaValue(field, feature, change.From, change.To)
+			if err != nil {
+				return result, err
+			}
+		}
+		state[field] = hash(change.To)
+	}
+
+	if s.Config.DryRun {
+		s.print(result)
+		return result, nil
+	}
+
+	if newState := saveState(state); newState != oldState {
+		if err := issue.SetData(StateLabel, newState); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// target decides which side needs to be updated: "aha", "github" or ""
+// for a conflict. 'last' is the hash of the value at the last sync.
+func (s *Syncer) target(dir Direction, last string, ahaVal string, gitVal string) string {
+	switch dir {
+	case AhaToGitHub:
+		return "github"
+	case GitHubToAha:
+		return "aha"
+	}
+
+	if last == "" {
+		if s.Config.InitialWinner == GitHubToAha {
+			return "aha"
+		}
+		return "github"
+	}
+
+	ahaChanged := hash(ahaVal) != last
+	gitChanged := hash(gitVal) != last
+
+	if ahaChanged && !gitChanged {
+		return "github"
+	}
+	if gitChanged && !ahaChanged {
+		return "aha"
+	}
+	return ""
+}
+
+func (s *Syncer) ahaValue(field string, feature *aha.Feature) string {
+	switch field {
+	case FieldTitle:
+		return strings.TrimSpace(feature.Name)
+	case FieldDescription:
+		return htmlToText(feature.Description.Body)
+	case FieldStatus:
+		if s.isClosedStatus(feature.Workflow_Status) {
+			return "closed"
+		}
+		return "open"
+	case FieldRelease:
+		if feature.Release == nil {
+			return ""
+		}
+		if mile, ok := s.Config.Releases[feature.Release.Name]; ok {
+			return mile
+		}
+		return feature.Release.Name
+	case FieldTags:
+		tags := append([]string{}, feature.Tags...)
+		sort.Strings(tags)
+		return strings.Join(tags, ",")
+	}
+	return ""
+}
+
+func (s *Syncer) gitValue(field string, issue *github.Issue, data *github.GitData) string {
+	switch field {
+	case FieldTitle:
+		return strings.TrimSpace(issue.Title)
+	case FieldDescription:
+		return normalizeText(strings.Join(data.Body, "\n"))
+	case FieldStatus:
+		return issue.State
+	case FieldRelease:
+		if issue.Milestone == nil {
+			return ""
+		}
+		return issue.Milestone.Title
+	case FieldTags:
+		tags := []string{}
+		for _, label := range issue.Labels {
+			if strings.HasPrefix(label.Name, s.Config.LabelPrefix) {
+				tags = append(tags, label.Name[len(s.Config.LabelPrefix):])
+			}
+		}
+		sort.Strings(tags)
+		return strings.Join(tags, ",")
+	}
+	return ""
+}
+
+func (s *Syncer) setGitValue(field string, issue *github.Issue, from string, to string) error {
+	switch field {
+	case FieldTitle:
+		return issue.SetTitle(to)
+	case FieldDescription:
+		// Leaves the data in the body alone
+		_, err := issue.UpdateGitData(func(data *github.GitData) error {
+			if normalizeText(strings.Join(data.Body, "\n")) != from {
+				return fmt.Errorf("%w: description of %s changed during "+
+					"the sync", github.ErrConflict, issue.HTML_URL)
+			}
+			data.Body = strings.Split(to, "\n")
+			return nil
+		})
+		return err
+	case FieldStatus:
+		if to == "closed" {
+			return issue.Close()
+		}
+		return issue.Reopen()
+	case FieldRelease:
+		return issue.SetMilestone(to)
+	case FieldTags:
+		add, remove := diffTags(from, to)
+		for _, tag := range add {
+			if err := issue.AddLabel(s.Config.LabelPrefix + tag); err != nil {
+				return err
+			}
+		}
+		for _, tag := range remove {
+			if err := issue.RemoveLabel(s.Config.LabelPrefix + tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+func (s *Syncer) setAhaValue(field string, feature *aha.Feature, from string, to string) error {
+	switch field {
+	case FieldTitle:
+		return feature.SetName(to)
+	case FieldDescription:
+		return feature.SetDescription(textToHTML(to))
+	case FieldStatus:
+		if to == "closed" {
+			return feature.SetStatus(s.Config.ClosedStatus)
+		}
+		return feature.SetStatus(s.Config.OpenStatus)
+	case FieldRelease:
+		if to == "" {
+			return fmt.Errorf("Can't remove the release of Aha feature %s",
+				feature.Reference_Num)
+		}
+		return feature.SetReleaseByName(s.releaseName(to))
+	case FieldTags:
+		add, remove := diffTags(from, to)
+		for _, tag := range add {
+			if err := feature.AddTag(tag); err != nil {
+				return err
+			}
+		}
+		for _, tag := range remove {
+			if err := feature.RemoveTag(tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+func (s *Syncer) isClosedStatus(status *aha.Workflow_Status) bool {
+	if status == nil {
+		return false
+	}
+	for _, name := range s.Config.ClosedStatuses {
+		if strings.EqualFold(name, status.Name) {
+			return true
+		}
+	}
+	return status.Complete
+}
+
+// releaseName maps a milestone title back to the Aha release name
+func (s *Syncer) releaseName(milestone string) string {
+	for rel, mile := range s.Config.Releases {
+		if mile == milestone {
+			return rel
+		}
+	}
+	return milestone
+}
+
+func (s *Syncer) print(result *Result) {
+	out := s.Config.Out
+	if out == nil {
+		out = os.Stdout
+	}
+
+	fmt.Fprintf(out, "%s <-> %s\n", result.Feature, result.Issue)
+	for _, c := range result.Changes {
+		fmt.Fprintf(out, "  update %s %s: %q -> %q\n", c.Target, c.Field,
+			c.From, c.To)
+	}
+	for _, c := range result.Conflicts {
+		fmt.Fprintf(out, "  CONFLICT %s: aha=%q github=%q\n", c.Field,
+			c.Aha, c.GitHub)
+	}
+}
+
+// diffTags returns the tags that are in 'to' but not 'from', and the
+// ones in 'from' but not in 'to'. Both are comma separated lists.
+func diffTags(from string, to string) ([]string, []string) {
+	split := func(str string) map[string]bool {
+		res := map[string]bool{}
+		for _, tag := range strings.Split(str, ",") {
+			if tag != "" {
+				res[tag] = true
+			}
+		}
+		return res
+	}
+	fromSet, toSet := split(from), split(to)
+
+	add, remove := []string{}, []string{}
+	for tag := range toSet {
+		if !fromSet[tag] {
+			add = append(add, tag)
+		}
+	}
+	for tag := range fromSet {
+		if !toSet[tag] {
+			remove = append(remove, tag)
+		}
+	}
+	sort.Strings(add)
+	sort.Strings(remove)
+	return add, remove
+}
+
+func hash(str string) string {
+	sum := sha256.Sum256([]byte(str))
+	return hex.EncodeToString(sum[:8])
+}
+
+// The sync state is a one line JSON map of Field -> hash of the value
+// both sides had at the end of the last sync
+func loadState(data *github.GitData) map[string]string {
+	state := map[string]string{}
+	for _, entry := range data.Data {
+		if entry[0] == StateLabel {
+			json.Unmarshal([]byte(entry[1]), &state)
+		}
+	}
+	return state
+}
+
+func saveState(state map[string]string) string {
+	buf, _ := json.Marshal(state) // map keys are sorted
+	return string(buf)
+}
+
+// Aha descriptions are HTML while issue bodies are markdown. Both sides
+// are compared as plain text: paragraphs separated by a blank line, line
+// breaks kept, other markup left as is (markdown) or dropped (HTML tags).
+
+var htmlBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</?(div|ul|ol|h[1-6]|pre|blockquote)[^>]*>`)
+var htmlItems = regexp.MustCompile(`(?i)<li(\s[^>]*)?>`)
+var htmlParas = regexp.MustCompile(`(?i)</?p(\s[^>]*)?>`)
+var htmlTags = regexp.MustCompile(`<[^>]*>`)
+
+// htmlToText turns an Aha description into text that can be compared
+// with an issue body
+func htmlToText(str string) string {
+	str = strings.ReplaceAll(str, "\r\n", "\n")
+	str = strings.ReplaceAll(str, "\n", " ")
+	str = htmlParas.ReplaceAllString(str, "\n\n")
+	str = htmlBreaks.ReplaceAllString(str, "\n")
+	str = htmlItems.ReplaceAllString(str, "\n- ")
+	str = htmlTags.ReplaceAllString(str, "")
+	return normalizeText(html.UnescapeString(str))
+}
+
+// textToHTML is the reverse of htmlToText, one <p> per paragraph
+func textToHTML(str string) string {
+	res := ""
+	for _, para := range strings.Split(normalizeText(str), "\n\n") {
+		if para == "" {
+			continue
+		}
+		lines := strings.Split(para, "\n")
+		for i, line := range lines {
+			lines[i] = html.EscapeString(line)
+		}
+		res += "<p>" + strings.Join(lines, "<br>") + "</p>"
+	}
+	return res
+}
+
+// normalizeText trims each line and the text, and squeezes runs of blank
+// lines down to one
+func normalizeText(str string) string {
+	lines := []string{}
+	blank := false
+	for _, line := range strings.Split(strings.ReplaceAll(str, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			blank = true
+			continue
+		}
+		if blank && len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		blank = false
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,103 @@
+// Package ahasync keeps an Aha Feature and the GitHub Issue it links to
+// (via the Feature's "ghe_url" custom field) in sync.
+package ahasync
+
+import (
+	"io"
+
+	"github.com/duglin/integration/github"
+)
+
+type Direction int
+
+const (
+	Skip        Direction = iota // don't sync this field
+	AhaToGitHub                  // Aha always wins
+	GitHubToAha                  // GitHub always wins
+	Both                         // whichever side changed, conflict if both
+)
+
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldStatus      = "status"
+	FieldRelease     = "release"
+	FieldTags        = "tags"
+)
+
+// Fields in the order they're synced
+var Fields = []string{FieldTitle, FieldDescription, FieldStatus,
+	FieldRelease, FieldTags}
+
+type Config struct {
+	Directions map[string]Direction // Field* -> Direction, missing = Skip
+
+	// Status. Aha statuses are mapped to "open" or "closed". Any status
+	// not in ClosedStatuses is "open" unless it's marked as Complete.
+	ClosedStatuses []string
+	OpenStatus     string // Aha status to use when the issue is reopened
+	ClosedStatus   string // Aha status to use when the issue is closed
+
+	// Release. Aha release name -> milestone title. Releases not in the
+	// map use the same name for the milestone.
+	Releases map[string]string
+
+	// Tags. Only labels with this prefix are synced, and the prefix is
+	// removed to get the Aha tag name (e.g. "aha/" + "ui" -> tag "ui")
+	LabelPrefix string
+
+	// First sync of a field that's different on both sides (and there's no
+	// previous state to know who changed) uses this side, default is Aha
+	InitialWinner Direction
+
+	DryRun bool      // just print what would be done
+	Out    io.Writer // where DryRun output goes, default is stdout
+}
+
+// DefaultConfig syncs everything both ways
+func DefaultConfig() Config {
+	return Config{
+		Directions: map[string]Direction{
+			FieldTitle:       Both,
+			FieldDescription: Both,
+			FieldStatus:      Both,
+			FieldRelease:     Both,
+			FieldTags:        Both,
+		},
+		OpenStatus:    "In development",
+		ClosedStatus:  "Shipped",
+		InitialWinner: AhaToGitHub,
+	}
+}
+
+type Syncer struct {
+	*github.GitHubClient
+
+	Config Config
+}
+
+// Change is one update that was made (or would be made in DryRun mode)
+type Change struct {
+	Field  string
+	Target string // "aha" or "github"
+	From   string
+	To     string
+}
+
+// Conflict is a field that was changed on both sides since the last sync.
+// Neither side is updated, someone needs to fix it by hand.
+type Conflict struct {
+	Field  string
+	Aha    string
+	GitHub string
+}
+
+type Result struct {
+	Feature   string // Aha reference num
+	Issue     string // GitHub issue URL
+	Changes   []*Change
+	Conflicts []*Conflict
+}
+
+// The GitData label used to save the state of the last sync on the issue
+const StateLabel = "Aha-Sync"
@@ -0,0 +1,455 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/duglin/integration/github"
+)
+
+// ErrPermission is returned (wrapped) when the sender isn't allowed to
+// run a command
+var ErrPermission = errors.New("permission denied")
+
+func NewDispatcher() *Dispatcher {
+	d := &Dispatcher{
+		Prefix:   "/",
+		Replies:  true,
+		commands: map[string]*Command{},
+	}
+	d.Register(&Command{
+		Name:       "help",
+		Help:       "Show this list of commands",
+		Permission: Anyone(),
+		Run: func(ctx *Context) (string, error) {
+			return d.Help(), nil
+		},
+	})
+	return d
+}
+
+func (d *Dispatcher) Register(cmd *Command) error {
+	name := strings.ToLower(cmd.Name)
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("Invalid command name %q", cmd.Name)
+	}
+	if cmd.Run == nil {
+		return fmt.Errorf("Command %q has no Run func", cmd.Name)
+	}
+	if _, ok := d.commands[name]; ok {
+		return fmt.Errorf("Command %q is already registered", cmd.Name)
+	}
+	for i, arg := range cmd.Args {
+		if arg.Variadic && i != len(cmd.Args)-1 {
+			return fmt.Errorf("Command %q: only the last arg can be variadic",
+				cmd.Name)
+		}
+		if !arg.Optional && i > 0 && cmd.Args[i-1].Optional {
+			return fmt.Errorf("Command %q: %q can't follow an optional arg",
+				cmd.Name, arg.Name)
+		}
+	}
+
+	d.commands[name] = cmd
+	d.order = append(d.order, name)
+	return nil
+}
+
+// Attach runs the Dispatcher on every issue_comment event of the router.
+// Errors go to the router's OnError handler.
+func (d *Dispatcher) Attach(wr *github.WebhookRouter) {
+	wr.OnIssueComment(func(e *github.Event_Issue_Comment) error {
+		if _, err := d.Handle(e); err != nil {
+			return fmt.Errorf("Error running commands: %s", err)
+		}
+		return nil
+	})
+}
+
+// Usage is "/name <arg> [opt] [list...]"
+func (d *Dispatcher) Usage(cmd *Command) string {
+	usage := d.Prefix + cmd.Name
+	for _, arg := range cmd.Args {
+		name := arg.Name
+		if arg.Kind == ArgUser {
+			name = "@" + name
+		}
+		if arg.Variadic {
+			name += "..."
+		}
+		if arg.Optional {
+			usage += " [" + name + "]"
+		} else {
+			usage += " <" + name + ">"
+		}
+	}
+	return usage
+}
+
+// Help is a markdown list of all commands
+func (d *Dispatcher) Help() string {
+	names := append([]string{}, d.order...)
+	sort.Strings(names)
+
+	help := "Available commands:\n"
+	for _, name := range names {
+		cmd := d.commands[name]
+		help += fmt.Sprintf("- `%s` - %s\n", d.Usage(cmd), cmd.Help)
+		for _, arg := range cmd.Args {
+			if arg.Help != "" {
+				help += fmt.Sprintf("  - `%s`: %s\n", arg.Name, arg.Help)
+			}
+		}
+	}
+	return help
+}
+
+// Handle runs the commands in the comment. For an edited comment only the
+// command lines that weren't in the old version (Changes.Body.From) are
+// run. Comments by bots are ignored so bots can't loop. Each result is
+// reported with a reaction on the comment and, if Replies is set, all of
+// the replies are posted as one comment.
+func (d *Dispatcher) Handle(e *github.Event_Issue_Comment) ([]*Result, error) {
+	if e.Comment == nil || e.Issue == nil {
+		return nil, nil
+	}
+	if e.Action != "created" && e.Action != "edited" {
+		return nil, nil
+	}
+	if e.Sender != nil && e.Sender.Type == "Bot" {
+		return nil, nil
+	}
+
+	lines := d.parseLines(e.Comment.Body)
+	if e.Action == "edited" {
+		old := map[string]bool{}
+		for _, line := range d.parseLines(e.Changes.Body.From) {
+			old[line] = true
+		}
+		newLines := []string{}
+		for _, line := range lines {
+			if !old[line] {
+				newLines = append(newLines, line)
+			}
+		}
+		lines = newLines
+	}
+
+	results := []*Result{}
+	for _, line := range lines {
+		if result := d.run(e, line); result != nil {
+			results = append(results, result)
+		}
+	}
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	return results, d.report(e, results)
+}
+
+// parseLines returns the lines of the body that start with the Prefix,
+// skipping code blocks
+func (d *Dispatcher) parseLines(body string) []string {
+	lines := []string{}
+	inCode := false
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || !strings.HasPrefix(line, d.Prefix) ||
+			len(line) == len(d.Prefix) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// run returns nil if the line isn't one of our commands
+func (d *Dispatcher) run(e *github.Event_Issue_Comment, line string) *Result {
+	words, err := splitWords(line[len(d.Prefix):])
+	if err != nil || len(words) == 0 {
+		return nil
+	}
+
+	cmd, ok := d.commands[strings.ToLower(words[0])]
+	if !ok {
+		return nil // not for us
+	}
+
+	ctx := &Context{
+		Event:   e,
+		Issue:   e.Issue,
+		Line:    line,
+		Command: cmd,
+	}
+	if e.Sender != nil {
+		ctx.Sender = e.Sender.Login
+	}
+
+	result := &Result{Line: line}
+
+	if ctx.args, err = parseArgs(cmd, words[1:]); err != nil {
+		result.Err = fmt.Errorf("%s\nUsage: `%s`", err, d.Usage(cmd))
+		return result
+	}
+
+	perm := cmd.Permission
+	if perm == nil {
+		perm = Collaborator()
+	}
+	ok, err = perm(ctx)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if !ok {
+		result.Err = fmt.Errorf("%w: @%s can't run %s%s", ErrPermission,
+			ctx.Sender, d.Prefix, cmd.Name)
+		return result
+	}
+
+	result.Reply, result.Err = cmd.Run(ctx)
+	return result
+}
+
+func (d *Dispatcher) report(e *github.Event_Issue_Comment, results []*Result) error {
+	reaction := "+1"
+	reply := ""
+	for _, result := range results {
+		text := result.Reply
+		if result.Err != nil {
+			reaction = "confused"
+			text = "Error: " + result.Err.Error()
+		}
+		if text != "" {
+			reply += fmt.Sprintf("> %s\n\n%s\n\n", result.Line, text)
+		}
+	}
+
+	if err := e.Comment.AddReaction(reaction); err != nil {
+		return err
+	}
+	if d.Replies && reply != "" {
+		return e.Issue.AddComment(strings.TrimSpace(reply))
+	}
+	return nil
+}
+
+// splitWords splits on spaces but keeps "quoted words" together
+func splitWords(line string) ([]string, error) {
+	words := []string{}
+	word, inQuote, hasWord := "", false, false
+
+	for _, ch := range line {
+		switch {
+		case ch == '"':
+			inQuote = !inQuote
+			hasWord = true
+		case !inQuote && (ch == ' ' || ch == '\t'):
+			if hasWord {
+				words = append(words, word)
+			}
+			word, hasWord = "", false
+		default:
+			word += string(ch)
+			hasWord = true
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("Missing closing quote")
+	}
+	if hasWord {
+		words = append(words, word)
+	}
+	return words, nil
+}
+
+func parseArgs(cmd *Command, words []string) (map[string][]string, error) {
+	args := map[string][]string{}
+
+	for i, arg := range cmd.Args {
+		if i >= len(words) {
+			if !arg.Optional {
+				return nil, fmt.Errorf("Missing %q", arg.Name)
+			}
+			continue
+		}
+
+		values := words[i : i+1]
+		if arg.Variadic {
+			values = words[i:]
+		}
+		for j, value := range values {
+			switch arg.Kind {
+			case ArgUser:
+				if len(value) < 2 || value[0] != '@' {
+					return nil, fmt.Errorf("%q needs to be a @user, not %q",
+						arg.Name, value)
+				}
+				values[j] = value[1:]
+			case ArgNumber:
+				if _, err := strconv.Atoi(value); err != nil {
+					return nil, fmt.Errorf("%q needs to be a number, not %q",
+						arg.Name, value)
+				}
+			}
+		}
+		args[arg.Name] = values
+	}
+
+	last := len(cmd.Args) > 0 && cmd.Args[len(cmd.Args)-1].Variadic
+	if !last && len(words) > len(cmd.Args) {
+		return nil, fmt.Errorf("Too many args")
+	}
+
+	return args, nil
+}
+
+// Arg returns the value of the arg, "" if it wasn't given. For variadic
+// args it's the first one, see List.
+func (ctx *Context) Arg(name string) string {
+	if values := ctx.args[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (ctx *Context) Has(name string) bool {
+	return len(ctx.args[name]) > 0
+}
+
+func (ctx *Context) Number(name string) int {
+	num, _ := strconv.Atoi(ctx.Arg(name))
+	return num
+}
+
+func (ctx *Context) List(name string) []string {
+	return ctx.args[name]
+}
+
+// Permissions
+
+func Anyone() Permission {
+	return func(ctx *Context) (bool, error) {
+		return true, nil
+	}
+}
+
+// Collaborator allows the repo's owner, members of its org and its
+// collaborators, based on the comment's author_association. It's used for
+// Commands without a Permission.
+func Collaborator() Permission {
+	return func(ctx *Context) (bool, error) {
+		if ctx.Event.Comment == nil {
+			return false, nil
+		}
+		switch ctx.Event.Comment.Author_Association {
+		case "OWNER", "MEMBER", "COLLABORATOR":
+			return true, nil
+		}
+		return false, nil
+	}
+}
+
+// OrgMember allows members of the repo's org. Private members are only
+// seen if the Token's user is in the org too.
+func OrgMember() Permission {
+	return func(ctx *Context) (bool, error) {
+		if ctx.Event.Organization == nil {
+			return false, nil
+		}
+		return ctx.Event.Organization.HasMember(ctx.Sender)
+	}
+}
+
+// TeamMember allows members of the team (slug) in the repo's org
+func TeamMember(team string) Permission {
+	return func(ctx *Context) (bool, error) {
+		if ctx.Event.Organization == nil {
+			return false, nil
+		}
+		return ctx.Event.Organization.IsTeamMember(ctx.Sender, team)
+	}
+}
+
+// Author allows the person who opened the issue or PR
+func Author() Permission {
+	return func(ctx *Context) (bool, error) {
+		return ctx.Issue.User != nil &&
+			strings.EqualFold(ctx.Issue.User.Login, ctx.Sender), nil
+	}
+}
+
+// AnyOf allows the sender if any of the permissions do
+func AnyOf(perms ...Permission) Permission {
+	return func(ctx *Context) (bool, error) {
+		for _, perm := range perms {
+			ok, err := perm(ctx)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// Common commands
+
+// Assign: /assign @user...
+func Assign(perm Permission) *Command {
+	return &Command{
+		Name:       "assign",
+		Help:       "Assign the issue to the users",
+		Args:       []Arg{{Name: "user", Kind: ArgUser, Variadic: true}},
+		Permission: perm,
+		Run: func(ctx *Context) (string, error) {
+			for _, user := range ctx.List("user") {
+				if err := ctx.Issue.AddAssignee(user); err != nil {
+					return "", err
+				}
+			}
+			return "", nil
+		},
+	}
+}
+
+// Label: /label name...
+func Label(perm Permission) *Command {
+	return &Command{
+		Name:       "label",
+		Help:       "Add the labels to the issue",
+		Args:       []Arg{{Name: "label", Variadic: true}},
+		Permission: perm,
+		Run: func(ctx *Context) (string, error) {
+			for _, label := range ctx.List("label") {
+				if err := ctx.Issue.AddLabel(label); err != nil {
+					return "", err
+				}
+			}
+			return "", nil
+		},
+	}
+}
+
+// Milestone: /milestone title
+func Milestone(perm Permission) *Command {
+	return &Command{
+		Name:       "milestone",
+		Help:       "Set the milestone of the issue",
+		Args:       []Arg{{Name: "milestone", Help: "quote titles with spaces"}},
+		Permission: perm,
+		Run: func(ctx *Context) (string, error) {
+			return "", ctx.Issue.SetMilestone(ctx.Arg("milestone"))
+		},
+	}
+}
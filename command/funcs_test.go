@@ -0,0 +1,232 @@
+package command
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/duglin/integration/github"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		line  string
+		words []string
+	}{
+		{"label bug", []string{"label", "bug"}},
+		{"  label \t bug  ", []string{"label", "bug"}},
+		{`label "needs info" bug`, []string{"label", "needs info", "bug"}},
+		{`label ""`, []string{"label", ""}},
+		{`say a"b c"d`, []string{"say", "ab cd"}},
+		{"", []string{}},
+	}
+	for _, test := range tests {
+		words, err := splitWords(test.line)
+		if err != nil || !reflect.DeepEqual(words, test.words) {
+			t.Errorf("%q: got %q/%v, want %q", test.line, words, err,
+				test.words)
+		}
+	}
+
+	if _, err := splitWords(`label "bug`); err == nil {
+		t.Errorf("missing quote wasn't an error")
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	cmd := &Command{
+		Name: "test",
+		Args: []Arg{
+			{Name: "user", Kind: ArgUser},
+			{Name: "num", Kind: ArgNumber, Optional: true},
+			{Name: "rest", Optional: true, Variadic: true},
+		},
+	}
+
+	tests := []struct {
+		words []string
+		args  map[string][]string
+		err   string
+	}{
+		{[]string{"@bob"}, map[string][]string{"user": {"bob"}}, ""},
+		{[]string{"@bob", "5", "a", "b"}, map[string][]string{
+			"user": {"bob"}, "num": {"5"}, "rest": {"a", "b"}}, ""},
+		{[]string{}, nil, `Missing "user"`},
+		{[]string{"bob"}, nil, `"user" needs to be a @user`},
+		{[]string{"@"}, nil, `"user" needs to be a @user`},
+		{[]string{"@bob", "five"}, nil, `"num" needs to be a number`},
+	}
+	for _, test := range tests {
+		args, err := parseArgs(cmd, test.words)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%q: got %v, want %q", test.words, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(args, test.args) {
+			t.Errorf("%q: got %v/%v, want %v", test.words, args, err,
+				test.args)
+		}
+	}
+
+	cmd.Args = cmd.Args[:1]
+	if _, err := parseArgs(cmd, []string{"@bob", "extra"}); err == nil {
+		t.Errorf("too many args wasn't an error")
+	}
+
+	// Variadic user args all get their "@" removed
+	cmd.Args = []Arg{{Name: "user", Kind: ArgUser, Variadic: true}}
+	args, err := parseArgs(cmd, []string{"@a", "@b"})
+	if err != nil || !reflect.DeepEqual(args["user"], []string{"a", "b"}) {
+		t.Errorf("variadic users: got %v/%v", args, err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	d := NewDispatcher()
+	run := func(ctx *Context) (string, error) { return "", nil }
+
+	bad := []*Command{
+		{Name: "", Run: run},
+		{Name: "two words", Run: run},
+		{Name: "norun"},
+		{Name: "HELP", Run: run},
+		{Name: "x", Run: run, Args: []Arg{{Name: "a", Variadic: true}, {Name: "b"}}},
+		{Name: "y", Run: run, Args: []Arg{{Name: "a", Optional: true}, {Name: "b"}}},
+	}
+	for _, cmd := range bad {
+		if err := d.Register(cmd); err == nil {
+			t.Errorf("%q was registered", cmd.Name)
+		}
+	}
+
+	if err := d.Register(&Command{Name: "ok", Run: run}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	d := NewDispatcher()
+	body := "Hi\n  /label bug\n/\n```\n/not this\n```\nsee /foo\n/assign @me"
+	want := []string{"/label bug", "/assign @me"}
+	if got := d.parseLines(body); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func testEvent(sender, association, author string) *github.Event_Issue_Comment {
+	return &github.Event_Issue_Comment{
+		Action: "created",
+		Sender: &github.User{Login: sender},
+		Issue:  &github.Issue{User: &github.User{Login: author}},
+		Comment: &github.Comment{
+			Author_Association: association,
+		},
+	}
+}
+
+func TestRunPermissions(t *testing.T) {
+	d := NewDispatcher()
+	ran := []string{}
+	for name, perm := range map[string]Permission{
+		"default": nil,
+		"author":  Author(),
+		"either":  AnyOf(Author(), Collaborator()),
+		"anyone":  Anyone(),
+	} {
+		name := name
+		d.Register(&Command{
+			Name:       name,
+			Permission: perm,
+			Run: func(ctx *Context) (string, error) {
+				ran = append(ran, name+":"+ctx.Sender)
+				return "done", nil
+			},
+		})
+	}
+
+	tests := []struct {
+		cmd         string
+		sender      string
+		association string
+		ok          bool
+	}{
+		{"default", "bob", "COLLABORATOR", true},
+		{"default", "bob", "MEMBER", true},
+		{"default", "bob", "OWNER", true},
+		{"default", "bob", "CONTRIBUTOR", false},
+		{"default", "bob", "NONE", false},
+		{"author", "alice", "NONE", true},
+		{"author", "bob", "OWNER", false},
+		{"either", "alice", "NONE", true},
+		{"either", "bob", "MEMBER", true},
+		{"either", "bob", "NONE", false},
+		{"anyone", "bob", "NONE", true},
+		{"help", "bob", "NONE", true},
+	}
+	for _, test := range tests {
+		ran = ran[:0]
+		e := testEvent(test.sender, test.association, "alice")
+		result := d.run(e, "/"+test.cmd)
+		if result == nil {
+			t.Fatalf("%s wasn't run", test.cmd)
+		}
+		if test.ok && result.Err != nil {
+			t.Errorf("%s by %s/%s: %v", test.cmd, test.sender,
+				test.association, result.Err)
+		}
+		if !test.ok && !errors.Is(result.Err, ErrPermission) {
+			t.Errorf("%s by %s/%s: got %v, want ErrPermission", test.cmd,
+				test.sender, test.association, result.Err)
+		}
+		if !test.ok && len(ran) != 0 {
+			t.Errorf("%s by %s/%s was run", test.cmd, test.sender,
+				test.association)
+		}
+	}
+
+	// Not ours
+	if d.run(testEvent("bob", "OWNER", "alice"), "/unknown") != nil {
+		t.Errorf("unknown command was run")
+	}
+
+	// Bad args are reported with the usage, before the permission check
+	d.Register(&Command{
+		Name: "num",
+		Args: []Arg{{Name: "n", Kind: ArgNumber}},
+		Run:  func(ctx *Context) (string, error) { return "", nil },
+	})
+	result := d.run(testEvent("bob", "NONE", "alice"), "/num x")
+	if result == nil || result.Err == nil ||
+		!strings.Contains(result.Err.Error(), "Usage: `/num <n>`") {
+		t.Errorf("bad args: got %+v", result)
+	}
+}
+
+func TestHandleSkips(t *testing.T) {
+	d := NewDispatcher()
+
+	// Bots are ignored
+	e := testEvent("ci", "OWNER", "alice")
+	e.Sender.Type = "Bot"
+	e.Comment.Body = "/help"
+	if results, err := d.Handle(e); err != nil || len(results) != 0 {
+		t.Errorf("bot: got %v/%v", results, err)
+	}
+
+	// Edits only run the new lines, there are none here
+	e = testEvent("bob", "OWNER", "alice")
+	e.Action = "edited"
+	e.Comment.Body = "/help\nmore text"
+	e.Changes.Body.From = "/help"
+	if results, err := d.Handle(e); err != nil || len(results) != 0 {
+		t.Errorf("edit: got %v/%v", results, err)
+	}
+
+	e.Action = "deleted"
+	if results, err := d.Handle(e); err != nil || len(results) != 0 {
+		t.Errorf("delete: got %v/%v", results, err)
+	}
+}
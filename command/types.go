@@ -0,0 +1,66 @@
+// Package command runs slash commands (e.g. "/label bug") found in GitHub
+// issue and PR comments. Commands declare their arguments and who's allowed
+// to run them, and the result is reported back on the comment.
+package command
+
+import (
+	"github.com/duglin/integration/github"
+)
+
+type ArgKind int
+
+const (
+	ArgString ArgKind = iota
+	ArgUser           // @login, the "@" is removed
+	ArgNumber         // an integer
+)
+
+type Arg struct {
+	Name     string
+	Kind     ArgKind
+	Help     string
+	Optional bool // only the trailing args can be optional
+	Variadic bool // the last arg can take all of the remaining words
+}
+
+// Permission decides if the sender of the comment may run the command
+type Permission func(ctx *Context) (bool, error)
+
+type Command struct {
+	Name       string // w/o the "/"
+	Help       string
+	Args       []Arg
+	Permission Permission // nil means Collaborator()
+
+	// Run returns the text to reply with, "" means just react to the
+	// comment
+	Run func(ctx *Context) (string, error)
+}
+
+// Context is what a Command's Run is given
+type Context struct {
+	Event   *github.Event_Issue_Comment
+	Issue   *github.Issue
+	Sender  string // login of the comment's author
+	Line    string // the command line as typed
+	Command *Command
+
+	args map[string][]string
+}
+
+// Dispatcher finds the commands in comments and runs them. Use Attach to
+// hook it up to a WebhookRouter.
+type Dispatcher struct {
+	Prefix  string // default is "/"
+	Replies bool   // reply with the result, not just react to the comment
+
+	commands map[string]*Command
+	order    []string
+}
+
+// Result is the outcome of one command line
+type Result struct {
+	Line  string
+	Reply string
+	Err   error
+}
@@ -0,0 +1,234 @@
+package github
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// CachedResponse is what's saved for a GET so that it can be sent as a
+// conditional request next time. A 304 doesn't count against the rate
+// limit and is answered with the saved Body.
+type CachedResponse struct {
+	URL           string `json:"url"`
+	ETag          string `json:"etag,omitempty"`
+	Last_Modified string `json:"last_modified,omitempty"`
+	Link          string `json:"link,omitempty"`
+	Body          []byte `json:"body"`
+}
+
+// Cache stores GET responses by URL. See WithCache, MemoryCache and
+// DiskCache.
+type Cache interface {
+	Get(url string) *CachedResponse // nil if not there
+	Set(url string, res *CachedResponse)
+	Delete(url string)
+	Keys() []string
+}
+
+// WithCache turns on conditional requests (If-None-Match and
+// If-Modified-Since) for GETs, using 'cache' to hold the responses
+func WithCache(cache Cache) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.cache = cache
+	}
+}
+
+// Invalidate removes everything cached for the resource at 'url': the
+// resource itself (any query), its sub-resources and the resources it's
+// part of. e.g. a write to .../issues/5/labels drops .../issues/5 and
+// .../issues?state=open.
+func (gh *GitHubClient) Invalidate(url string) {
+	if gh.Cache == nil {
+		return
+	}
+	path := cachePath(url)
+	for _, key := range gh.Cache.Keys() {
+		keyPath := cachePath(key)
+		if strings.HasPrefix(keyPath, path) || strings.HasPrefix(path, keyPath) {
+			gh.Cache.Delete(key)
+		}
+	}
+}
+
+// cachePath is the URL w/o the query, with a trailing / so that
+// ".../issues/1" isn't seen as part of ".../issues/10"
+func cachePath(str string) string {
+	if u, err := url.Parse(str); err == nil {
+		u.RawQuery = ""
+		u.Fragment = ""
+		str = u.String()
+	}
+	return strings.TrimSuffix(str, "/") + "/"
+}
+
+// MemoryCache keeps the most recent 'size' responses in memory
+type MemoryCache struct {
+	mutex sync.Mutex
+	size  int
+	order *list.List // front is the newest
+	items map[string]*list.Element
+}
+
+func NewMemoryCache(size int) *MemoryCache {
+	if size <= 0 {
+		size = 1
+	}
+	return &MemoryCache{
+		size:  size,
+		order: list.New(),
+		items: map[string]*list.Element{},
+	}
+}
+
+func (mc *MemoryCache) Get(url string) *CachedResponse {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	elem, ok := mc.items[url]
+	if !ok {
+		return nil
+	}
+	mc.order.MoveToFront(elem)
+	return elem.Value.(*CachedResponse)
+}
+
+func (mc *MemoryCache) Set(url string, res *CachedResponse) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if elem, ok := mc.items[url]; ok {
+		elem.Value = res
+		mc.order.MoveToFront(elem)
+		return
+	}
+
+	mc.items[url] = mc.order.PushFront(res)
+	for mc.order.Len() > mc.size {
+		oldest := mc.order.Back()
+		mc.order.Remove(oldest)
+		delete(mc.items, oldest.Value.(*CachedResponse).URL)
+	}
+}
+
+func (mc *MemoryCache) Delete(url string) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	if elem, ok := mc.items[url]; ok {
+		mc.order.Remove(elem)
+		delete(mc.items, url)
+	}
+}
+
+func (mc *MemoryCache) Keys() []string {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	keys := []string{}
+	for key := range mc.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// DiskCache saves each response as a JSON file in Dir so that it
+// survives restarts. The URLs are indexed in memory so Keys doesn't have
+// to read every file, the index is loaded from Dir on first use. Files
+// added by other processes after that aren't seen by Keys.
+type DiskCache struct {
+	Dir string
+
+	mutex sync.Mutex
+	urls  map[string]bool // nil until loaded
+}
+
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DiskCache{Dir: dir}, nil
+}
+
+func (dc *DiskCache) file(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dc.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (dc *DiskCache) read(file string) *CachedResponse {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	res := &CachedResponse{}
+	if err = json.Unmarshal(buf, res); err != nil {
+		return nil
+	}
+	return res
+}
+
+func (dc *DiskCache) Get(url string) *CachedResponse {
+	res := dc.read(dc.file(url))
+	if res == nil || res.URL != url {
+		return nil
+	}
+	return res
+}
+
+// Set is best effort, a response that can't be saved just isn't cached
+func (dc *DiskCache) Set(url string, res *CachedResponse) {
+	buf, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+	file := dc.file(url)
+	tmp := file + ".tmp"
+	if err = ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return
+	}
+	if err = os.Rename(tmp, file); err != nil {
+		return
+	}
+
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+	if dc.urls != nil {
+		dc.urls[url] = true
+	}
+}
+
+func (dc *DiskCache) Delete(url string) {
+	os.Remove(dc.file(url))
+
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+	delete(dc.urls, url)
+}
+
+func (dc *DiskCache) Keys() []string {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if dc.urls == nil {
+		dc.urls = map[string]bool{}
+		files, _ := filepath.Glob(filepath.Join(dc.Dir, "*.json"))
+		for _, file := range files {
+			if res := dc.read(file); res != nil {
+				dc.urls[res.URL] = true
+			}
+		}
+	}
+
+	keys := []string{}
+	for key := range dc.urls {
+		keys = append(keys, key)
+	}
+	return keys
+}
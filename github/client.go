@@ -0,0 +1,116 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/duglin/integration/internal/httpclient"
+)
+
+type clientConfig struct {
+	httpclient.Config
+
+	retry      *RetryPolicy
+	oldSecrets []string
+	cache      Cache
+	dataStore  DataStore
+}
+
+// ClientOption configures how a GitHubClient talks to the server.
+// See NewGitHubClientE.
+type ClientOption func(*clientConfig)
+
+// WithHTTPClient uses the supplied http.Client as is. All other HTTP
+// options are ignored when this one is present.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(cfg *clientConfig) { cfg.HTTPClient = client }
+}
+
+// WithTransport uses the supplied RoundTripper instead of building one.
+// The CA, proxy and TLS options are ignored when this one is present.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(cfg *clientConfig) { cfg.Transport = rt }
+}
+
+// WithCABundle adds the PEM encoded certificates to the set of trusted
+// root CAs (in addition to the system ones).
+func WithCABundle(pem []byte) ClientOption {
+	return func(cfg *clientConfig) { cfg.AddCABundle(pem) }
+}
+
+// WithCAFile is the same as WithCABundle but reads the PEM from a file.
+func WithCAFile(file string) ClientOption {
+	return func(cfg *clientConfig) { cfg.AddCAFile(file) }
+}
+
+// WithProxy sends all requests through the proxy at proxyURL. By default
+// the standard HTTPS_PROXY/NO_PROXY env vars are used.
+func WithProxy(proxyURL string) ClientOption {
+	return func(cfg *clientConfig) { cfg.SetProxy(proxyURL) }
+}
+
+// WithTimeout sets the overall timeout for each HTTP request.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(cfg *clientConfig) { cfg.Timeout = timeout }
+}
+
+// WithInsecureSkipVerify turns off TLS certificate verification. Only
+// use this for testing against servers with self-signed certs.
+func WithInsecureSkipVerify() ClientOption {
+	return func(cfg *clientConfig) { cfg.Insecure = true }
+}
+
+// WithOldSecrets adds webhook secrets that VerifyEvent will still accept
+// while deliveries are moved over to the new Secret
+func WithOldSecrets(secrets ...string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.oldSecrets = append(cfg.oldSecrets, secrets...)
+	}
+}
+
+func newClientConfig(opts []ClientOption) *clientConfig {
+	cfg := &clientConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithContext returns a copy of the client that uses ctx for every
+// request. Since all resources (Issue, Repository, ...) hold onto the client
+// that fetched them, anything retrieved through the copy inherits ctx too:
+//
+//	repo, err := gh.WithContext(ctx).GetRepository("org", "repo")
+//	issues, err := repo.GetIssues("state=open") // stops when ctx is done
+func (gh *GitHubClient) WithContext(ctx context.Context) *GitHubClient {
+	newGH := *gh
+	newGH.ctx = ctx
+	return &newGH
+}
+
+func (gh *GitHubClient) context() context.Context {
+	if gh.ctx != nil {
+		return gh.ctx
+	}
+	return context.Background()
+}
+
+// sleep waits for d unless ctx is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (gh *GitHubClient) httpClient() *http.Client {
+	if gh.HTTPClient != nil {
+		return gh.HTTPClient
+	}
+	return httpclient.Default
+}
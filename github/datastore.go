@@ -0,0 +1,265 @@
+package github
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// DataStore is where the GetData/AddData/SetData/HasData/DeleteData
+// family keeps an issue's GitData. The default is BodyStore, use
+// WithDataStore to pick another one.
+type DataStore interface {
+	Load(issue *Issue) (*GitData, error)
+
+	// Update does a read-modify-write of the data and returns what was
+	// written. 'fn' may be called more than once.
+	Update(issue *Issue, fn func(data *GitData) error) (*GitData, error)
+}
+
+func WithDataStore(store DataStore) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.dataStore = store
+	}
+}
+
+func (gh *GitHubClient) dataStore() DataStore {
+	if gh.DataStore != nil {
+		return gh.DataStore
+	}
+	return BodyStore{}
+}
+
+// LoadData returns the issue's GitData from the client's DataStore. Body
+// isn't set, for the text of the issue use GetGitData.
+func (issue *Issue) LoadData() (*GitData, error) {
+	return issue.dataStore().Load(issue)
+}
+
+// loadData is for the funcs that can't return an error, a failure is
+// logged and looks like there's no data
+func (issue *Issue) loadData() *GitData {
+	data, err := issue.dataStore().Load(issue)
+	if err != nil {
+		log.Printf("Error loading data of %s: %s", issue.HTML_URL, err)
+		return &GitData{}
+	}
+	return data
+}
+
+// BodyStore keeps the GitData in the metadata block of the issue body
+type BodyStore struct{}
+
+func (BodyStore) Load(issue *Issue) (*GitData, error) {
+	data := issue.GetGitData()
+	data.Body = nil
+	return data, nil
+}
+
+func (BodyStore) Update(issue *Issue, fn func(data *GitData) error) (*GitData, error) {
+	return issue.UpdateGitData(fn)
+}
+
+// DefaultDataMarker identifies the CommentStore's comment
+const DefaultDataMarker = "<!-- gitdata -->"
+
+// CommentStore keeps the GitData in a single comment on the issue, found
+// by its Marker, so changes don't show up as edits of the issue body. The
+// comment has the same metadata block as the body would. It's created when
+// data is first written, until then the data in the issue body (in either
+// format) is used, and the first write moves it into the comment.
+type CommentStore struct {
+	Marker string // default is DefaultDataMarker
+	User   string // only trust comments by this login, default is ours
+}
+
+func (cs CommentStore) marker() string {
+	if cs.Marker != "" {
+		return cs.Marker
+	}
+	return DefaultDataMarker
+}
+
+// find returns the oldest data comment, or nil if there isn't one yet
+func (cs CommentStore) find(issue *Issue) (*Comment, error) {
+	user := cs.User
+	if user == "" {
+		login, err := issue.GetLogin()
+		if err != nil {
+			return nil, err
+		}
+		user = login
+	}
+
+	comments, err := issue.GetComments()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, comment := range comments {
+		if !strings.HasPrefix(comment.Body, cs.marker()) {
+			continue
+		}
+		if comment.User == nil || !strings.EqualFold(comment.User.Login, user) {
+			continue
+		}
+		return comment, nil
+	}
+	return nil, nil
+}
+
+func (cs CommentStore) body(data *GitData) (string, error) {
+	md := data.metadata()
+	md.Body = cs.marker() + "\n" +
+		"_This comment is updated automatically, please don't edit it._"
+	return md.Encode()
+}
+
+// parse returns the data of the comment, w/o its text
+func (cs CommentStore) parse(comment *Comment) (*GitData, error) {
+	md, err := ParseMetadata(comment.Body)
+	if err != nil {
+		return nil, err
+	}
+	data := md.gitData()
+	data.Body = nil
+	return data, nil
+}
+
+func (cs CommentStore) Load(issue *Issue) (*GitData, error) {
+	comment, err := cs.find(issue)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil {
+		// Not migrated yet
+		return bodyData(issue)
+	}
+	return cs.parse(comment)
+}
+
+func (cs CommentStore) Update(issue *Issue, fn func(data *GitData) error) (*GitData, error) {
+	for try := 0; try < updateTries; try++ {
+		if try > 0 {
+			wait := time.Duration(try*100+rand.Intn(100)) * time.Millisecond
+			if err := sleep(issue.context(), wait); err != nil {
+				return nil, err
+			}
+		}
+
+		comment, err := cs.find(issue)
+		if err != nil {
+			return nil, err
+		}
+		if comment == nil {
+			data, ok, err := cs.create(issue, fn)
+			if err != nil || ok {
+				return data, err
+			}
+			continue // someone else's comment won, update that one
+		}
+
+		data, err := cs.parse(comment)
+		if err != nil {
+			return nil, err
+		}
+		orig, err := cs.body(data)
+		if err != nil {
+			return nil, err
+		}
+		if err = fn(data); err != nil {
+			return nil, err
+		}
+		body, err := cs.body(data)
+		if err != nil {
+			return nil, err
+		}
+		if body == orig {
+			return data, nil
+		}
+
+		check, err := issue.GetComment(comment.URL)
+		if err != nil {
+			return nil, err
+		}
+		if check.Updated_At != comment.Updated_At || check.Body != comment.Body {
+			continue // changed while we were busy, try again
+		}
+
+		if err = comment.Edit(body); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("%w: data comment of %s kept changing",
+		ErrConflict, issue.HTML_URL)
+}
+
+// create makes the data comment, moving any data in the issue body into
+// it. The body is only cleaned up once the comment exists so nothing is
+// lost if we fail half way. If another writer created a comment at the
+// same time the oldest one is kept, ours is deleted and false is returned.
+func (cs CommentStore) create(issue *Issue, fn func(data *GitData) error) (*GitData, bool, error) {
+	if err := issue.Refresh(); err != nil {
+		return nil, false, err
+	}
+
+	data, err := bodyData(issue)
+	if err != nil {
+		return nil, false, err
+	}
+	labels := data.metadata().Keys()
+	if err = fn(data); err != nil {
+		return nil, false, err
+	}
+
+	body, err := cs.body(data)
+	if err != nil {
+		return nil, false, err
+	}
+	comment, err := issue.CreateComment(body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	first, err := cs.find(issue)
+	if err != nil {
+		return nil, false, err
+	}
+	if first != nil && first.ID != comment.ID {
+		return nil, false, comment.Delete()
+	}
+
+	if len(labels) > 0 {
+		if err = cleanBody(issue, labels); err != nil {
+			return nil, false, err
+		}
+	}
+	return data, true, nil
+}
+
+// bodyData returns the data still in the issue body, w/o its text
+func bodyData(issue *Issue) (*GitData, error) {
+	md, err := issue.GetMetadata()
+	if err != nil {
+		return nil, err
+	}
+	data := md.gitData()
+	data.Body = nil
+	return data, nil
+}
+
+// cleanBody removes the labels that were moved into the comment from the
+// issue body
+func cleanBody(issue *Issue, labels []string) error {
+	_, err := issue.UpdateGitData(func(data *GitData) error {
+		for _, label := range labels {
+			data.DeleteData(label, "")
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,113 @@
+package github
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommentStoreMigration(t *testing.T) {
+	md := &Metadata{Body: "Text"}
+	md.Set("Aha", "APP-1")
+	md.Set("Note", "first\nsecond")
+	md.Set("Count", 3)
+	body, _ := md.Encode()
+	body += "\n---\n**_Legacy_**: old\n"
+
+	f, issue := newFakeIssue(t, body, WithDataStore(CommentStore{}))
+
+	// Someone else's comment with the marker isn't trusted
+	f.comment("mallory", DefaultDataMarker+"\n<!-- metadata\n"+
+		`{"version": 1, "data": {"Aha": "APP-666"}}`+"\n-->\n")
+
+	// Until the first write the body's data is used
+	if !issue.HasData("Aha", "APP-1") || !issue.HasData("Legacy", "old") {
+		t.Fatalf("body data wasn't loaded")
+	}
+
+	if err := issue.AddData("New", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.comments) != 2 {
+		t.Fatalf("got %d comments, want 2", len(f.comments))
+	}
+	comment := f.comments[1]
+	if !strings.HasPrefix(comment.Body, DefaultDataMarker) {
+		t.Errorf("comment is missing the marker:\n%s", comment.Body)
+	}
+	md, err := ParseMetadata(comment.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"Aha": "APP-1", "Legacy": "old", "New": "value",
+		"Note": "first\nsecond",
+	} {
+		if got := md.GetString(key); got != want {
+			t.Errorf("comment %s: got %q, want %q", key, got, want)
+		}
+	}
+	count := 0
+	if md.Get("Count", &count); count != 3 {
+		t.Errorf("typed value lost: %s", md.Values["Count"])
+	}
+
+	// The moved data is gone from the body, the text stays
+	if f.body != "Text" {
+		t.Errorf("body wasn't cleaned up: %q", f.body)
+	}
+
+	// From now on the comment is used
+	issue.Refresh()
+	data, err := issue.LoadData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.HasData("New", "value") || !data.HasData("Aha", "APP-1") ||
+		data.Body != nil {
+		t.Errorf("bad data from the comment: %+v", data)
+	}
+
+	if err = issue.SetData("Aha", "APP-2"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.comments) != 2 || !issue.HasData("Aha", "APP-2") ||
+		issue.HasData("Aha", "APP-1") {
+		t.Errorf("comment wasn't updated:\n%s", f.comments[1].Body)
+	}
+	if md, _ = ParseMetadata(f.comments[1].Body); md.GetString("Note") != "first\nsecond" {
+		t.Errorf("multi-line value lost on update:\n%s", f.comments[1].Body)
+	}
+	if f.body != "Text" {
+		t.Errorf("body was changed: %q", f.body)
+	}
+}
+
+func TestCommentStoreRace(t *testing.T) {
+	f, issue := newFakeIssue(t, "Text", WithDataStore(CommentStore{}))
+
+	// Another writer creates the comment while we're creating ours
+	f.onGet = func(f *fakeIssue, n int) {
+		if n == 1 {
+			data := &GitData{}
+			data.AddData("Theirs", "x")
+			body, _ := CommentStore{}.body(data)
+			f.comment("bot", body)
+		}
+	}
+
+	if err := issue.AddData("Ours", "y"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.comments) != 1 {
+		t.Fatalf("got %d comments, want 1", len(f.comments))
+	}
+	data, err := CommentStore{}.parse(f.comments[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.HasData("Theirs", "x") || !data.HasData("Ours", "y") {
+		t.Errorf("an update was lost:\n%s", f.comments[0].Body)
+	}
+}
@@ -0,0 +1,96 @@
+package github
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DeliveryCache remembers the most recent X-GitHub-Delivery IDs so that
+// redelivered events can be ignored. Once it's full the oldest IDs are
+// dropped. For a delivery that failed it also remembers which of its
+// handlers already succeeded so they aren't run again on the retry.
+type DeliveryCache struct {
+	mutex sync.Mutex
+	size  int
+	order *list.List // front is the newest
+	ids   map[string]*list.Element
+}
+
+type delivery struct {
+	id      string
+	retry   bool         // failed, let the next Seen through
+	handled map[int]bool // handlers that succeeded
+}
+
+func NewDeliveryCache(size int) *DeliveryCache {
+	if size <= 0 {
+		size = 1
+	}
+	return &DeliveryCache{
+		size:  size,
+		order: list.New(),
+		ids:   map[string]*list.Element{},
+	}
+}
+
+// Seen records 'id' and returns true if it was already in the cache, and
+// not Forgotten since
+func (dc *DeliveryCache) Seen(id string) bool {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if elem, ok := dc.ids[id]; ok {
+		dc.order.MoveToFront(elem)
+		d := elem.Value.(*delivery)
+		if !d.retry {
+			return true
+		}
+		d.retry = false
+		return false
+	}
+
+	dc.ids[id] = dc.order.PushFront(&delivery{id: id})
+	for dc.order.Len() > dc.size {
+		oldest := dc.order.Back()
+		dc.order.Remove(oldest)
+		delete(dc.ids, oldest.Value.(*delivery).id)
+	}
+	return false
+}
+
+// Forget makes the next Seen of 'id' return false so a redelivery of it
+// will be processed again, e.g. after a failure. The handlers marked as
+// Handled stay that way.
+func (dc *DeliveryCache) Forget(id string) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if elem, ok := dc.ids[id]; ok {
+		elem.Value.(*delivery).retry = true
+	}
+}
+
+// Handled records that handler 'n' (in registration order) of delivery
+// 'id' succeeded
+func (dc *DeliveryCache) Handled(id string, n int) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if elem, ok := dc.ids[id]; ok {
+		d := elem.Value.(*delivery)
+		if d.handled == nil {
+			d.handled = map[int]bool{}
+		}
+		d.handled[n] = true
+	}
+}
+
+func (dc *DeliveryCache) IsHandled(id string, n int) bool {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if elem, ok := dc.ids[id]; ok {
+		return elem.Value.(*delivery).handled[n]
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Use these with errors.Is() to check what kind of error Git() returned:
+//
+//	if _, err := gh.Git("GET", url, ""); errors.Is(err, github.ErrNotFound) {
+var (
+	ErrUnauthorized = errors.New("github: unauthorized")
+	ErrForbidden    = errors.New("github: forbidden")
+	ErrNotFound     = errors.New("github: not found")
+	ErrValidation   = errors.New("github: validation failed")
+	ErrRateLimited  = errors.New("github: rate limited")
+	ErrServer       = errors.New("github: server error")
+
+	// Returned by the Update* funcs when the resource kept changing
+	ErrConflict = errors.New("github: concurrent update conflict")
+)
+
+// FieldError is one entry of the "errors" array of a GitHub error response
+type FieldError struct {
+	Resource string
+	Field    string
+	Code     string
+	Message  string
+}
+
+// APIError is returned by Git() (and GraphQL()) for any non-2xx response
+type APIError struct {
+	StatusCode        int
+	Message           string
+	Errors            []FieldError
+	Documentation_URL string
+	RateLimited       bool
+
+	Method  string
+	URL     string
+	ReqBody string
+	Body    []byte // raw response body
+}
+
+func newAPIError(method, url, reqBody string, res *http.Response, rl RateLimit, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode:  res.StatusCode,
+		RateLimited: isRateLimited(res, rl, body),
+		Method:      method,
+		URL:         url,
+		ReqBody:     reqBody,
+		Body:        body,
+	}
+
+	gitErr := struct {
+		Message           string
+		Errors            []FieldError
+		Documentation_URL string
+	}{}
+	if err := json.Unmarshal(body, &gitErr); err == nil {
+		apiErr.Message = gitErr.Message
+		apiErr.Errors = gitErr.Errors
+		apiErr.Documentation_URL = gitErr.Documentation_URL
+	}
+
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Github: Error %s: %d %s\nReq Body: %s\n", e.URL,
+		e.StatusCode, string(e.Body), e.ReqBody)
+}
+
+// HasFieldError returns true if any of the field errors has this message
+func (e *APIError) HasFieldError(message string) bool {
+	for _, fe := range e.Errors {
+		if fe.Message == message {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == 401
+	case ErrForbidden:
+		return e.StatusCode == 403 && !e.RateLimited
+	case ErrNotFound:
+		return e.StatusCode == 404
+	case ErrValidation:
+		return e.StatusCode == 422
+	case ErrRateLimited:
+		return e.RateLimited
+	case ErrServer:
+		return e.StatusCode/100 == 5
+	}
+	return false
+}
@@ -1,21 +1,24 @@
 package github
 
 import (
-	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
-	"crypto/tls"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"net/http"
+	"net/url"
 	"reflect"
-	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func (u *User) SetGH(gh *GitHubClient) {
@@ -167,13 +170,83 @@ func (e *Card) SetGH(gh *GitHubClient) {
 	}
 }
 
+func (e *Event_Pull_Request) SetGH(gh *GitHubClient) {
+	if e != nil {
+		e.GitHubClient = gh
+		e.Pull_Request.SetGH(gh)
+		e.Requested_Reviewer.SetGH(gh)
+		e.Requested_Team.SetGH(gh)
+		e.Assignee.SetGH(gh)
+		e.Label.SetGH(gh)
+		e.Repository.SetGH(gh)
+		e.Organization.SetGH(gh)
+		e.Sender.SetGH(gh)
+	}
+}
+
+func (e *Event_Pull_Request_Review) SetGH(gh *GitHubClient) {
+	if e != nil {
+		e.GitHubClient = gh
+		e.Review.SetGH(gh)
+		e.Pull_Request.SetGH(gh)
+		e.Repository.SetGH(gh)
+		e.Organization.SetGH(gh)
+		e.Sender.SetGH(gh)
+	}
+}
+
+func (pr *PullRequest) SetGH(gh *GitHubClient) {
+	if pr != nil {
+		pr.GitHubClient = gh
+		pr.User.SetGH(gh)
+		for _, l := range pr.Labels {
+			l.SetGH(gh)
+		}
+		pr.Milestone.SetGH(gh)
+		pr.Assignee.SetGH(gh)
+		for _, a := range pr.Assignees {
+			a.SetGH(gh)
+		}
+		for _, r := range pr.Requested_Reviewers {
+			r.SetGH(gh)
+		}
+		for _, t := range pr.Requested_Teams {
+			t.SetGH(gh)
+		}
+		pr.Head.SetGH(gh)
+		pr.Base.SetGH(gh)
+		pr.Merged_By.SetGH(gh)
+	}
+}
+
+func (ref *PullRequestRef) SetGH(gh *GitHubClient) {
+	if ref != nil {
+		ref.GitHubClient = gh
+		ref.User.SetGH(gh)
+		ref.Repo.SetGH(gh)
+	}
+}
+
+func (r *Review) SetGH(gh *GitHubClient) {
+	if r != nil {
+		r.GitHubClient = gh
+		r.User.SetGH(gh)
+	}
+}
+
 type GitResponse struct {
 	StatusCode int
 	Links      map[string]string
 	Body       []byte
+	RateLimit  RateLimit
+	Cached     bool // Body came from the Cache after a 304
 }
 
 func (gh *GitHubClient) Git(method string, url string, body string) (*GitResponse, error) {
+	return gh.GitContext(gh.context(), method, url, body)
+}
+
+func (gh *GitHubClient) GitContext(ctx context.Context, method string, url string, body string) (*GitResponse, error) {
 	if gh.Token == "" {
 		return nil, fmt.Errorf("Missing GitHub Token, perhaps .gitToken is missing?")
 	}
@@ -191,35 +264,82 @@ func (gh *GitHubClient) Git(method string, url string, body string) (*GitRespons
 		Links: map[string]string{},
 	}
 
-	buf := []byte{}
-	if body != "" {
-		buf = []byte(body)
-	}
-	req, err := http.NewRequest(method, url, bytes.NewReader(buf))
-	if err != nil {
-		log.Printf("Git: %s %s", method, url)
-		return nil, err
+	client := gh.httpClient()
+	policy := gh.retryPolicy()
+
+	var cached *CachedResponse
+	if gh.Cache != nil && method == "GET" {
+		cached = gh.Cache.Get(url)
 	}
 
-	auth := base64.StdEncoding.EncodeToString([]byte("user:" + gh.Token))
-	req.Header.Add("Authorization", "Basic "+auth)
-	req.Header.Add("Content-Type", "application/json")
+	var res *http.Response
+	buf := []byte{}
 
-	if strings.Contains(url, "projects") || strings.Contains(url, "cards") ||
-		strings.Contains(url, "columns") {
-		req.Header.Add("Accept", "application/vnd.GitHubClient.inertia-preview+json")
-	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, url,
+			strings.NewReader(body))
+		if err != nil {
+			log.Printf("Git: %s %s", method, url)
+			return nil, err
+		}
+
+		auth := base64.StdEncoding.EncodeToString([]byte("user:" + gh.Token))
+		req.Header.Add("Authorization", "Basic "+auth)
+		req.Header.Add("Content-Type", "application/json")
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		if strings.Contains(url, "projects") || strings.Contains(url, "cards") ||
+			strings.Contains(url, "columns") {
+			req.Header.Add("Accept", "application/vnd.GitHubClient.inertia-preview+json")
+		}
+		if strings.HasSuffix(url, "/reactions") {
+			req.Header.Add("Accept", "application/vnd.github.squirrel-girl-preview+json")
+		}
+		if cached != nil {
+			if cached.ETag != "" {
+				req.Header.Add("If-None-Match", cached.ETag)
+			}
+			if cached.Last_Modified != "" {
+				req.Header.Add("If-Modified-Since", cached.Last_Modified)
+			}
+		}
+
+		res, err = client.Do(req)
+		if err != nil {
+			wait, ok := policy.retryWait(method, nil, RateLimit{}, nil, attempt)
+			if ok && ctx.Err() == nil {
+				if err = sleep(ctx, wait); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, err
+		}
+		buf, _ = ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		gitResponse.RateLimit = parseRateLimit(res.Header)
+		if res.StatusCode/100 == 2 || res.StatusCode == http.StatusNotModified {
+			break
+		}
+
+		wait, ok := policy.retryWait(method, res, gitResponse.RateLimit, buf, attempt)
+		if !ok {
+			break
+		}
+		log.Printf("Git: %s %s got %d, retrying in %s", method, url,
+			res.StatusCode, wait)
+		if err = sleep(ctx, wait); err != nil {
+			return nil, err
+		}
 	}
 
-	res, err := (&http.Client{Transport: tr}).Do(req)
-	if err != nil {
-		return nil, err
+	linkHeader := res.Header.Get("Link")
+	if res.StatusCode == http.StatusNotModified && cached != nil {
+		res.StatusCode = http.StatusOK
+		buf = cached.Body
+		linkHeader = cached.Link
+		gitResponse.Cached = true
 	}
-	defer res.Body.Close()
-	buf, _ = ioutil.ReadAll(res.Body)
 
 	gitResponse.StatusCode = res.StatusCode
 	gitResponse.Body = buf
@@ -227,15 +347,34 @@ func (gh *GitHubClient) Git(method string, url string, body string) (*GitRespons
 	if res.StatusCode/100 != 2 {
 		// fmt.Printf("Git Error:\n--> %s %s\n--> %s\n", method, url, body)
 		// fmt.Printf("%d %s\n", res.StatusCode, string(buf))
-		return &gitResponse,
-			fmt.Errorf("Github: Error %s: %d %s\nReq Body: %s\n", url,
-				res.StatusCode, string(buf), body)
+		return &gitResponse, newAPIError(method, url, body, res,
+			gitResponse.RateLimit, buf)
+	}
+
+	if gh.Cache != nil {
+		if method != "GET" {
+			// GraphQL POSTs don't say what they touch, and most are reads
+			if url != gh.graphQLURL() {
+				gh.Invalidate(url)
+			}
+		} else if !gitResponse.Cached {
+			etag, mod := res.Header.Get("ETag"), res.Header.Get("Last-Modified")
+			if etag != "" || mod != "" {
+				gh.Cache.Set(url, &CachedResponse{
+					URL:           url,
+					ETag:          etag,
+					Last_Modified: mod,
+					Link:          linkHeader,
+					Body:          buf,
+				})
+			}
+		}
 	}
 
 	// Link: <https://.../issues?page=2>; rel="next",
 	//   <https://issues?page=2>; rel="last"
-	if links := res.Header["Link"]; len(links) > 0 {
-		links = strings.Split(links[0], ",")
+	if linkHeader != "" {
+		links := strings.Split(linkHeader, ",")
 		for _, link := range links {
 			parts := strings.Split(link, ";")
 			for i, part := range parts {
@@ -254,106 +393,86 @@ func (gh *GitHubClient) Git(method string, url string, body string) (*GitRespons
 
 // daItem is an empty slice of the resource type to return (e.g. []*Issue{})
 func (gh *GitHubClient) GetAll(url string, daItem interface{}) (interface{}, error) {
+	return gh.GetAllContext(gh.context(), url, daItem)
+}
+
+// GetAllContext stops fetching pages as soon as ctx is done
+func (gh *GitHubClient) GetAllContext(ctx context.Context, url string, daItem interface{}) (interface{}, error) {
 	daType := reflect.TypeOf(daItem)
 	result := reflect.MakeSlice(daType, 0, 0)
 
-	for url != "" {
-		var res *GitResponse
-		var err error
-		if res, err = gh.Git("GET", url, ""); err != nil {
-			return nil, err
-		}
-
+	pager := gh.newPager(ctx, url)
+	for !pager.Done() {
 		// Create a pointer Value to a slice, JSON Unmarshal needs a ptr
 		itemsPtr := reflect.New(daType)
 
 		// Create an empty slice Value and make our pointer reference it
 		itemsPtr.Elem().Set(reflect.MakeSlice(daType, 0, 0))
 
-		err = json.Unmarshal(res.Body, itemsPtr.Interface())
-		if err != nil {
+		if err := pager.NextPage(itemsPtr.Interface()); err != nil {
 			return nil, err
 		}
 
 		// Re-get the pointer Value of the slice since it may have moved,
 		// then append it to the result set
 		result = reflect.AppendSlice(result, itemsPtr.Elem())
-
-		url = res.Links["next"]
 	}
 
 	return result.Interface(), nil
 }
 
 func (gh *GitHubClient) GraphQL(cmd string) (map[string]interface{}, error) {
-	buf := []byte{}
-	resMap := map[string]interface{}{}
+	return gh.GraphQLContext(gh.context(), cmd)
+}
 
-	js := struct {
-		Query string `json:"query"`
-	}{
-		Query: cmd,
-	}
+func (gh *GitHubClient) GraphQLContext(ctx context.Context, cmd string) (map[string]interface{}, error) {
+	resMap := map[string]interface{}{}
 
-	buf, err := json.Marshal(js)
+	buf, err := gh.graphQL(ctx, cmd, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	url := "https://api." + gh.Host + "/graphql"
+	err = json.Unmarshal(buf, &resMap)
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(buf))
-	if err != nil {
-		log.Printf("GitQL: %s", url)
-		return nil, err
-	}
+	return resMap, err
+}
 
-	auth := base64.StdEncoding.EncodeToString([]byte("user:" + gh.Token))
-	req.Header.Add("Authorization", "Basic "+auth)
-	req.Header.Add("Content-Type", "application/json")
+// VerifyEvent checks the signature of a webhook delivery against Secret
+// and OldSecrets. X-Hub-Signature-256 is used when present, the legacy
+// SHA-1 X-Hub-Signature only when it isn't.
+func (gh *GitHubClient) VerifyEvent(req *http.Request, body []byte) bool {
+	hash, sig := sha256.New, req.Header.Get("X-Hub-Signature-256")
+	prefix, size := "sha256=", sha256.Size
 
-	if strings.Contains(url, "projects") || strings.Contains(url, "cards") ||
-		strings.Contains(url, "columns") {
-		req.Header.Add("Accept", "application/vnd.github.inertia-preview+json")
-		req.Header.Add("Accept", "application/vnd.github.v3+json")
+	if sig == "" {
+		hash, sig = sha1.New, req.Header.Get("X-Hub-Signature")
+		prefix, size = "sha1=", sha1.Size
 	}
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	if len(sig) != len(prefix)+2*size || !strings.HasPrefix(sig, prefix) {
+		return false
 	}
 
-	res, err := (&http.Client{Transport: tr}).Do(req)
+	calc, err := hex.DecodeString(sig[len(prefix):])
 	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
-	buf, _ = ioutil.ReadAll(res.Body)
-
-	if res.StatusCode/100 != 2 {
-		return nil,
-			fmt.Errorf("GitQL: Error %s: %d %s\nReq Body: %s\n", url,
-				res.StatusCode, string(buf), cmd)
-	}
-
-	err = json.Unmarshal(buf, &resMap)
-
-	return resMap, err
-}
-
-func (gh *GitHubClient) VerifyEvent(req *http.Request, body []byte) bool {
-	sig := req.Header.Get("X-HUB-SIGNATURE")
-
-	if len(sig) != 45 || !strings.HasPrefix(sig, "sha1=") {
 		return false
 	}
 
-	calc := make([]byte, 20)
-	hex.Decode(calc, []byte(sig[5:]))
+	secrets := append([]string{gh.Secret}, gh.OldSecrets...)
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		mac := hmac.New(hash, []byte(secret))
+		mac.Write(body)
 
-	mac := hmac.New(sha1.New, []byte(gh.Secret))
-	mac.Write(body)
+		if hmac.Equal(calc, mac.Sum(nil)) {
+			return true
+		}
+	}
 
-	return hmac.Equal(calc, mac.Sum(nil))
+	return false
 }
 
 func Body(str string) string {
@@ -377,12 +496,16 @@ func (issue *Issue) SetTitle(title string) error {
 }
 
 func (issue *Issue) AddLabel(label string) error {
-	_, err := issue.Git("POST", issue.URL+"/labels", `{"labels": [ "`+label+`"]}`)
+	buf, err := json.Marshal(map[string][]string{"labels": {label}})
+	if err != nil {
+		return err
+	}
+	_, err = issue.Git("POST", issue.URL+"/labels", string(buf))
 	return err
 }
 
 func (issue *Issue) RemoveLabel(label string) error {
-	_, err := issue.Git("DELETE", issue.URL+"/labels/"+label, "")
+	_, err := issue.Git("DELETE", issue.URL+"/labels/"+url.PathEscape(label), "")
 	return err
 }
 
@@ -396,10 +519,158 @@ func (issue *Issue) HasLabel(label string) bool {
 }
 
 func (issue *Issue) AddComment(comment string) error {
-	_, err := issue.Git("POST", issue.URL+"/comments", Body(comment))
+	_, err := issue.CreateComment(comment)
+	return err
+}
+
+// CreateComment is AddComment but it returns the new Comment
+func (issue *Issue) CreateComment(body string) (*Comment, error) {
+	res, err := issue.Git("POST", issue.URL+"/comments", Body(body))
+	if err != nil {
+		return nil, err
+	}
+
+	comment := Comment{}
+	if err = json.Unmarshal(res.Body, &comment); err != nil {
+		return nil, err
+	}
+	comment.SetGH(issue.GitHubClient)
+
+	return &comment, nil
+}
+
+func (issue *Issue) GetComments() ([]*Comment, error) {
+	url := issue.Comments_URL
+	if url == "" {
+		url = issue.URL + "/comments"
+	}
+
+	items, err := issue.GetAll(url, []*Comment{})
+	if err != nil {
+		return nil, err
+	}
+
+	comments := items.([]*Comment)
+	for _, comment := range comments {
+		comment.SetGH(issue.GitHubClient)
+	}
+
+	return comments, nil
+}
+
+// /repos/:owner/:repo/issues/comments/:comment_id
+func (gh *GitHubClient) GetComment(url string) (*Comment, error) {
+	res, err := gh.Git("GET", url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	comment := Comment{}
+	if err = json.Unmarshal(res.Body, &comment); err != nil {
+		return nil, err
+	}
+	comment.SetGH(gh)
+
+	return &comment, nil
+}
+
+// FindComment returns our first comment that starts with 'marker', or
+// nil. Comments by anyone else are ignored so they can't take its place.
+func (issue *Issue) FindComment(marker string) (*Comment, error) {
+	login, err := issue.GetLogin()
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := issue.GetComments()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, comment := range comments {
+		if comment.User == nil || !strings.EqualFold(comment.User.Login, login) {
+			continue
+		}
+		if strings.HasPrefix(comment.Body, marker) {
+			return comment, nil
+		}
+	}
+	return nil, nil
+}
+
+// UpsertComment updates the comment that starts with 'marker' (usually an
+// HTML comment like "<!-- build-status -->") or creates one if there isn't
+// one yet. The marker is added to the top of 'body' if it's not there.
+func (issue *Issue) UpsertComment(marker string, body string) (*Comment, error) {
+	if !strings.HasPrefix(body, marker) {
+		body = marker + "\n" + body
+	}
+
+	comment, err := issue.FindComment(marker)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil {
+		return issue.CreateComment(body)
+	}
+	if comment.Body != body {
+		if err = comment.Edit(body); err != nil {
+			return nil, err
+		}
+	}
+	return comment, nil
+}
+
+func (comment *Comment) Edit(body string) error {
+	res, err := comment.Git("PATCH", comment.URL, Body(body))
+	if err != nil {
+		return err
+	}
+
+	newComment := Comment{}
+	if err = json.Unmarshal(res.Body, &newComment); err != nil {
+		return err
+	}
+	newComment.SetGH(comment.GitHubClient)
+
+	*comment = newComment
+	return nil
+}
+
+func (comment *Comment) Delete() error {
+	_, err := comment.Git("DELETE", comment.URL, "")
+	return err
+}
+
+// Reactions that can be added to issues and comments
+var Reactions = []string{"+1", "-1", "laugh", "confused", "heart", "hooray",
+	"rocket", "eyes"}
+
+func addReaction(gh *GitHubClient, url string, content string) error {
+	valid := false
+	for _, r := range Reactions {
+		valid = valid || r == content
+	}
+	if !valid {
+		return fmt.Errorf("Invalid reaction %q", content)
+	}
+
+	buf, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return err
+	}
+	_, err = gh.Git("POST", url+"/reactions", string(buf))
 	return err
 }
 
+func (comment *Comment) AddReaction(content string) error {
+	return addReaction(comment.GitHubClient, comment.URL, content)
+}
+
+func (issue *Issue) AddReaction(content string) error {
+	return addReaction(issue.GitHubClient, issue.URL, content)
+}
+
 func (issue *Issue) Close() error {
 	_, err := issue.Git("PATCH", issue.URL, `{"state":"closed"}`)
 	return err
@@ -453,7 +724,11 @@ func (issue *Issue) AddAssignee(user string) error {
 	if len(user) > 1 && user[0] == '@' {
 		user = user[1:]
 	}
-	_, err := issue.Git("POST", issue.URL+"/assignees", `{"assignees":["`+user+`"]}`)
+	buf, err := json.Marshal(map[string][]string{"assignees": {user}})
+	if err != nil {
+		return err
+	}
+	_, err = issue.Git("POST", issue.URL+"/assignees", string(buf))
 	return err
 }
 
@@ -461,7 +736,11 @@ func (issue *Issue) RemoveAssignee(user string) error {
 	if len(user) > 1 && user[0] == '@' {
 		user = user[1:]
 	}
-	_, err := issue.Git("DELETE", issue.URL+"/assignees", `{"assignees":["`+user+`"]}`)
+	buf, err := json.Marshal(map[string][]string{"assignees": {user}})
+	if err != nil {
+		return err
+	}
+	_, err = issue.Git("DELETE", issue.URL+"/assignees", string(buf))
 	return err
 }
 
@@ -497,9 +776,25 @@ func (org *Organization) IsMember(user string) (bool, error) {
 	if len(user) > 1 && user[0] == '@' {
 		user = user[1:]
 	}
-	res, err := org.Git("GET", org.URL+"/public_members/"+user, "")
+	_, err := org.Git("GET", org.URL+"/public_members/"+user, "")
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// HasMember is IsMember but it also sees private members, as long as the
+// Token's user is in the org. Otherwise GitHub redirects to the public list.
+func (org *Organization) HasMember(user string) (bool, error) {
+	if len(user) > 1 && user[0] == '@' {
+		user = user[1:]
+	}
+	_, err := org.Git("GET", org.URL+"/members/"+user, "")
 	if err != nil {
-		if res != nil && res.StatusCode == 404 {
+		if errors.Is(err, ErrNotFound) {
 			return false, nil
 		}
 		return false, err
@@ -514,7 +809,7 @@ func (org *Organization) IsTeamMember(user string, team string) (bool, error) {
 
 	res, err := org.Git("GET", org.URL+"/teams/"+team, "")
 	if err != nil {
-		if res != nil && res.StatusCode == 404 {
+		if errors.Is(err, ErrNotFound) {
 			return false, fmt.Errorf("Team %q not found", team)
 		}
 		return false, err
@@ -531,7 +826,7 @@ func (org *Organization) IsTeamMember(user string, team string) (bool, error) {
 	res, err = org.Git("GET", loc, "")
 	if err != nil {
 		log.Printf("Err: %s", err)
-		if res != nil && res.StatusCode == 404 {
+		if errors.Is(err, ErrNotFound) {
 			return false, nil
 		}
 		return false, err
@@ -556,12 +851,16 @@ func (repo *Repository) GetLabels() ([]*Label, error) {
 }
 
 func (repo *Repository) GetIssues(query string) ([]*Issue, error) {
+	return repo.GetIssuesContext(repo.context(), query)
+}
+
+func (repo *Repository) GetIssuesContext(ctx context.Context, query string) ([]*Issue, error) {
 	url := repo.URL + "/issues"
 	if query != "" {
 		url += "?" + query
 	}
 
-	items, err := repo.GetAll(url, []*Issue{})
+	items, err := repo.GetAllContext(ctx, url, []*Issue{})
 	if err != nil {
 		return nil, err
 	}
@@ -574,6 +873,53 @@ func (repo *Repository) GetIssues(query string) ([]*Issue, error) {
 	return issues, nil
 }
 
+// SearchIssues returns the issues (and PRs) that match the search
+// 'query', e.g. "repo:org/name is:open label:bug". GitHub only returns the
+// first 1000 and new issues can take a moment to show up.
+func (gh *GitHubClient) SearchIssues(query string) ([]*Issue, error) {
+	pager := gh.NewPager("/search/issues?q=" + url.QueryEscape(query))
+	issues := []*Issue{}
+	for !pager.Done() {
+		page := struct{ Items []*Issue }{}
+		if err := pager.NextPage(&page); err != nil {
+			return nil, err
+		}
+		issues = append(issues, page.Items...)
+	}
+
+	for _, issue := range issues {
+		issue.SetGH(gh)
+	}
+	return issues, nil
+}
+
+func (repo *Repository) CreateIssue(title string, body string) (*Issue, error) {
+	req := struct {
+		Title string `json:"title"`
+		Body  string `json:"body,omitempty"`
+	}{
+		Title: title,
+		Body:  body,
+	}
+	buf, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := repo.Git("POST", repo.URL+"/issues", string(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	issue := Issue{}
+	if err = json.Unmarshal(res.Body, &issue); err != nil {
+		return nil, err
+	}
+	issue.SetGH(repo.GitHubClient)
+
+	return &issue, nil
+}
+
 func (repo *Repository) GetMilestones(query string) ([]*Milestone, error) {
 	url := repo.URL + "/milestones"
 	if query != "" {
@@ -704,11 +1050,15 @@ func (gh *GitHubClient) GetIssueParts(org string, repo string, num int) (*Issue,
 }
 
 func (gh *GitHubClient) GetIssuesParts(org string, repo string, query string) ([]*Issue, error) {
+	return gh.GetIssuesPartsContext(gh.context(), org, repo, query)
+}
+
+func (gh *GitHubClient) GetIssuesPartsContext(ctx context.Context, org string, repo string, query string) ([]*Issue, error) {
 	url := fmt.Sprintf("/repos/%s/%s/issues", org, repo)
 	if query != "" {
 		url += "?" + query
 	}
-	items, err := gh.GetAll(url, []*Issue{})
+	items, err := gh.GetAllContext(ctx, url, []*Issue{})
 	if err != nil {
 		return nil, err
 	}
@@ -748,19 +1098,50 @@ func (gh *GitHubClient) GetRepositoryTeams(org string, repo string) ([]*Team, er
 	return teams, nil
 }
 
-func (gh *GitHubClient) IsUserInOrganization(org string, user string) (bool, error) {
-	_, err := gh.Git("GET", "/orgs/"+org+"/public_members/"+user, "")
+// GetLogin returns the login of the user the Token belongs to
+func (gh *GitHubClient) GetLogin() (string, error) {
+	if gh.me != nil {
+		gh.me.mu.Lock()
+		defer gh.me.mu.Unlock()
+		if gh.me.login != "" {
+			return gh.me.login, nil
+		}
+	}
+
+	res, err := gh.Git("GET", "/user", "")
 	if err != nil {
-		return false, err
+		return "", err
 	}
-	return true, nil
-}
 
-// Github Data Manipulation
+	user := User{}
+	if err = json.Unmarshal(res.Body, &user); err != nil {
+		return "", err
+	}
 
+	if gh.me != nil {
+		gh.me.login = user.Login
+	}
+	return user.Login, nil
+}
+
+func (gh *GitHubClient) IsUserInOrganization(org string, user string) (bool, error) {
+	_, err := gh.Git("GET", "/orgs/"+org+"/public_members/"+user, "")
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Github Data Manipulation
+
+// GitData is an issue body split into its text (Body) and its data as
+// label/text pairs. The data is kept in the body's metadata block, see
+// Metadata, a label with more than one text is saved as a list.
 type GitData struct {
 	Body []string
 	Data [][2]string
+
+	other map[string]json.RawMessage // metadata that isn't text, kept as is
 }
 
 type ByLabel [][2]string
@@ -786,16 +1167,24 @@ func (gd *GitData) AddData(label string, text string) {
 
 func (gd *GitData) DeleteData(label string, text string) bool {
 	res := false
-	for i, entry := range gd.Data {
+	data := [][2]string{}
+	for _, entry := range gd.Data {
 		if entry[0] == label && (text == "" || entry[1] == text) {
-			gd.Data = append(gd.Data[:i], gd.Data[i+1:]...)
 			res = true
+			continue
 		}
+		data = append(data, entry)
 	}
+	gd.Data = data
 	if len(gd.Data) == 0 {
 		gd.Data = nil
 	}
 
+	if _, ok := gd.other[label]; ok && text == "" {
+		delete(gd.other, label)
+		res = true
+	}
+
 	return res
 }
 
@@ -817,42 +1206,20 @@ func (issue *Issue) GetGitData() *GitData {
 	return ParseForGitData(issue.Body)
 }
 
+// ParseForGitData returns the GitData of an issue (or comment) body. If
+// the metadata can't be parsed (see ParseMetadata) it's logged and the
+// whole body is returned as text, writes will fail with the error.
 func ParseForGitData(comment string) *GitData {
-	data := &GitData{}
-
-	lines := strings.Split(comment, "\n")
-	for _, line := range lines {
-		// **_Title_**: text
-		i := strings.Index(line, "_**: ")
-		if i < 2 || !strings.HasPrefix(line, "**_") {
-			data.Body = append(data.Body, line)
-			continue
-		}
-
-		label, text := "", ""
-
-		label = strings.TrimSpace(line[3:i])
-		text = strings.TrimSpace(line[i+5:])
-
-		data.AddData(label, text)
-	}
-
-	// Remove trailing "---" || "" in Body
-	for len(data.Body) > 0 {
-		line := strings.TrimSpace(data.Body[len(data.Body)-1])
-
-		if line == "---" || line == "" {
-			data.Body = data.Body[:len(data.Body)-1]
-			continue
-		}
-		break
+	md, err := ParseMetadata(comment)
+	if err != nil {
+		log.Printf("Error parsing GitData: %s", err)
+		return &GitData{Body: strings.Split(comment, "\n")}
 	}
-
-	return data
+	return md.gitData()
 }
 
 func (issue *Issue) GetData(label string) []string {
-	data := issue.GetGitData()
+	data := issue.loadData()
 
 	var res []string = nil
 	for _, entry := range data.Data {
@@ -865,7 +1232,7 @@ func (issue *Issue) GetData(label string) []string {
 }
 
 func (issue *Issue) GetSingleData(label string) string {
-	data := issue.GetGitData()
+	data := issue.loadData()
 
 	for _, entry := range data.Data {
 		if entry[0] == label {
@@ -877,62 +1244,148 @@ func (issue *Issue) GetSingleData(label string) string {
 }
 
 func (issue *Issue) AddData(label string, text string) error {
-	data := issue.GetGitData()
-	data.AddData(label, text)
-	return issue.SetGitData(data)
+	_, err := issue.dataStore().Update(issue, func(data *GitData) error {
+		data.AddData(label, text)
+		return nil
+	})
+	return err
 }
 
 func (issue *Issue) DeleteData(label string, text string) error {
-	data := issue.GetGitData()
-	if data.DeleteData(label, text) {
-		return issue.SetGitData(data)
-	}
-	return nil
+	_, err := issue.dataStore().Update(issue, func(data *GitData) error {
+		data.DeleteData(label, text)
+		return nil
+	})
+	return err
 }
 
 func (issue *Issue) HasData(label string, text string) bool {
-	data := issue.GetGitData()
+	data := issue.loadData()
 	return data.HasData(label, text)
 }
 
 func (issue *Issue) SetData(label string, text string) error {
-	if d := issue.GetData(label); len(d) == 1 && d[0] == text {
+	_, err := issue.dataStore().Update(issue, func(data *GitData) error {
+		data.SetData(label, text)
 		return nil
-	}
-	data := issue.GetGitData()
-	data.SetData(label, text)
-	return issue.SetGitData(data)
+	})
+	return err
 }
 
+// SetGitData writes 'data', which is based on the issue's Body as it was
+// fetched, back to the issue. If someone else changed the body since then
+// it fails with ErrConflict, use UpdateGitData to merge with their change.
 func (issue *Issue) SetGitData(data *GitData) error {
-	body := ""
-
-	// Remove trailing "---" || "" in Body
-	for len(data.Body) > 0 {
-		line := strings.TrimSpace(data.Body[len(data.Body)-1])
+	body, err := data.body()
+	if err != nil {
+		return err
+	}
+	base := issue.Body
 
-		if line == "---" || line == "" {
-			data.Body = data.Body[:len(data.Body)-1]
-			continue
+	return issue.update(func(cur *Issue) (string, bool, error) {
+		if cur.Body != base {
+			return "", false, fmt.Errorf("%w: issue %s changed since it "+
+				"was fetched", ErrConflict, issue.HTML_URL)
+		}
+		md, err := ParseMetadata(cur.Body)
+		if err != nil {
+			return "", false, err
+		}
+		orig, err := md.gitData().body()
+		if err != nil {
+			return "", false, err
+		}
+		return body, body != orig, nil
+	})
+}
+
+// UpdateGitData refetches the issue, calls 'fn' to change its GitData and
+// then writes it back, unless nothing changed. If the issue was changed by
+// someone else in the meantime it starts over, so 'fn' may be called more
+// than once. Returns the GitData that was written.
+func (issue *Issue) UpdateGitData(fn func(data *GitData) error) (*GitData, error) {
+	var data *GitData
+	err := issue.update(func(cur *Issue) (string, bool, error) {
+		md, err := ParseMetadata(cur.Body)
+		if err != nil {
+			return "", false, err
+		}
+		data = md.gitData()
+		if err = fn(data); err != nil {
+			return "", false, err
+		}
+		// Compare with the body as we'd write it, not as it is, so a
+		// no-op isn't written just because the formatting differs
+		orig, err := md.gitData().body()
+		if err != nil {
+			return "", false, err
+		}
+		body, err := data.body()
+		if err != nil {
+			return "", false, err
 		}
-		break
+		return body, body != orig, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return data, nil
+}
 
-	for _, line := range data.Body {
-		body += line + "\n"
-	}
+// The number of times update() will try before giving up with ErrConflict
+const updateTries = 5
+
+// update does a read-modify-write of the issue's body. 'fn' is given a
+// fresh copy of the issue and returns the new body and whether it changed
+// anything, nothing is written if it didn't. The issue is fetched
+// again just before the write and if its Updated_At changed we start over.
+// GitHub has no conditional PATCH so this narrows the window for lost
+// updates rather than closing it.
+func (issue *Issue) update(fn func(cur *Issue) (string, bool, error)) error {
+	for try := 0; try < updateTries; try++ {
+		if try > 0 {
+			wait := time.Duration(try*100+rand.Intn(100)) * time.Millisecond
+			if err := sleep(issue.context(), wait); err != nil {
+				return err
+			}
+		}
+
+		cur, err := issue.GetIssue(issue.URL)
+		if err != nil {
+			return err
+		}
 
-	if len(data.Data) > 0 {
-		body += "\n---\n"
+		body, changed, err := fn(cur)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			*issue = *cur
+			return nil
+		}
 
-		sort.Sort(ByLabel(data.Data))
+		check, err := issue.GetIssue(issue.URL)
+		if err != nil {
+			return err
+		}
+		if check.Updated_At != cur.Updated_At || check.Body != cur.Body {
+			continue // changed while we were busy, try again
+		}
 
-		for _, entry := range data.Data {
-			body += fmt.Sprintf("**_%s_**: %s\n", entry[0], entry[1])
+		if err = cur.SetBody(body); err != nil {
+			return err
 		}
+		*issue = *cur
+		return nil
 	}
 
-	return issue.SetBody(body)
+	return fmt.Errorf("%w: issue %s kept changing", ErrConflict,
+		issue.HTML_URL)
+}
+
+// body returns the issue body for the GitData
+func (data *GitData) body() (string, error) {
+	return data.metadata().Encode()
 }
 
 func (issue *Issue) GetRepository() (*Repository, error) {
@@ -1029,52 +1482,139 @@ func (issue *Issue) GetProjectCards(name string) ([]*Card, error) {
 }
 
 func (issue *Issue) AddToProject(name string) error {
+	_, err := issue.AddToProjectColumn(name, "Under Review", CardTop, false)
+	return err
+}
+
+// AddToProjectColumn adds a card for the issue to the column of the
+// repo's project. If the column doesn't exist it's created when 'create'
+// is true. If the issue is already in the project its card is returned
+// as is.
+func (issue *Issue) AddToProjectColumn(project string, column string, pos CardPosition, create bool) (*Card, error) {
 	repo, err := issue.GetRepository()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	proj, err := repo.GetProject(name)
+	proj, err := repo.GetProject(project)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if proj == nil {
-		return fmt.Errorf("Can't find Project %q", name)
+		return nil, fmt.Errorf("Can't find Project %q", project)
 	}
-	col, err := proj.GetColumn("Under Review")
+
+	card, err := proj.AddCard(column, issue.ID, "Issue", pos, create)
+	apiErr := &APIError{}
+	if errors.As(err, &apiErr) &&
+		apiErr.HasFieldError("Project already has the associated issue") {
+		cards, err := issue.GetProjectCards(project)
+		if err != nil || len(cards) == 0 {
+			return nil, err
+		}
+		return cards[0], nil
+	}
+	return card, err
+}
+
+// AddToProjectColumn adds a card for the PR to the column of the base
+// repo's project, see Issue.AddToProjectColumn
+func (pr *PullRequest) AddToProjectColumn(project string, column string, pos CardPosition, create bool) (*Card, error) {
+	if pr.Base == nil || pr.Base.Repo == nil {
+		return nil, fmt.Errorf("PR #%d has no base repo", pr.Number)
+	}
+	proj, err := pr.Base.Repo.GetProject(project)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if proj == nil {
+		return nil, fmt.Errorf("Can't find Project %q", project)
+	}
+
+	card, err := proj.AddCard(column, pr.ID, "PullRequest", pos, create)
+	apiErr := &APIError{}
+	if errors.As(err, &apiErr) &&
+		apiErr.HasFieldError("Project already has the associated issue") {
+		issue, err := pr.GetIssue()
+		if err != nil {
+			return nil, err
+		}
+		cards, err := issue.GetProjectCards(project)
+		if err != nil || len(cards) == 0 {
+			return nil, err
+		}
+		return cards[0], nil
+	}
+	return card, err
+}
+
+// AddCard creates a card in the column for the content (contentType is
+// "Issue" or "PullRequest") and then moves it to 'pos'
+func (project *Project) AddCard(column string, contentID int, contentType string, pos CardPosition, create bool) (*Card, error) {
+	col, err := project.GetColumn(column)
+	if err != nil {
+		return nil, err
 	}
 	if col == nil {
-		return fmt.Errorf("Can't find Column 'Under Review'")
+		if !create {
+			return nil, fmt.Errorf("Can't find Column %q", column)
+		}
+		if col, err = project.CreateColumn(column); err != nil {
+			return nil, err
+		}
 	}
 
-	data := fmt.Sprintf(`{"note":null,"content_id":%d,"content_type":"Issue"}`,
-		issue.ID)
+	req := struct {
+		Note         *string `json:"note"`
+		Content_ID   int     `json:"content_id"`
+		Content_Type string  `json:"content_type"`
+	}{
+		Content_ID:   contentID,
+		Content_Type: contentType,
+	}
+	buf, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
 
-	res, err := repo.Git("POST", col.Cards_URL, data)
-	if res.Body != nil {
-		gitErr := struct {
-			Message string
-			Errors  []struct {
-				Resource string
-				Code     string
-				Field    string
-				Message  string
-			}
-			Documentation_URL string
-		}{}
-
-		err := json.Unmarshal(res.Body, &gitErr)
-		if err == nil {
-			if gitErr.Errors != nil {
-				if gitErr.Errors[0].Message == "Project already has the associated issue" {
-					return nil
-				}
-			}
+	res, err := project.Git("POST", col.Cards_URL, string(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	card := Card{}
+	if err = json.Unmarshal(res.Body, &card); err != nil {
+		return nil, err
+	}
+	card.SetGH(project.GitHubClient)
+
+	// New cards go to the top
+	if pos != "" && pos != CardTop {
+		if err = card.move(pos, 0); err != nil {
+			return &card, err
 		}
 	}
 
-	return err
+	return &card, nil
+}
+
+func (project *Project) CreateColumn(name string) (*Column, error) {
+	buf, err := json.Marshal(map[string]string{"name": name})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := project.Git("POST", project.Columns_URL, string(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	column := Column{}
+	if err = json.Unmarshal(res.Body, &column); err != nil {
+		return nil, err
+	}
+	column.SetGH(project.GitHubClient)
+
+	return &column, nil
 }
 
 func (issue *Issue) RemoveFromProject(name string) error {
@@ -1113,54 +1653,72 @@ func (card *Card) GetIssue() (*Issue, error) {
 	return card.GetIssueParts(parts[0], parts[1], num)
 }
 
+func CardAfter(card *Card) CardPosition {
+	return CardPosition(fmt.Sprintf("after:%d", card.ID))
+}
+
 func (card *Card) MoveToTop() error {
-	_, err := card.Git("POST", card.URL+"/moves", `{"position": "top"}`)
-	return err
+	return card.move(CardTop, 0)
 }
 
 func (card *Card) MoveToBottom() error {
-	_, err := card.Git("POST", card.URL+"/moves", `{"position": "bottom"}`)
-	return err
+	return card.move(CardBottom, 0)
 }
 
 func (card *Card) MoveAfter(otherCard *Card) error {
-	_, err := card.Git("POST", card.URL+"/moves",
-		fmt.Sprintf(`{"position": "after:%d"}`, otherCard.ID))
-	return err
+	return card.move(CardAfter(otherCard), 0)
 }
 
-func (issue *Issue) MoveToRepository(repoName string) error {
-	oldRepo, err := issue.GetRepository()
-	if err != nil {
+// MoveToColumn moves the card to 'pos' in another column of its project
+func (card *Card) MoveToColumn(column *Column, pos CardPosition) error {
+	if pos == "" {
+		pos = CardTop
+	}
+	if err := card.move(pos, column.ID); err != nil {
 		return err
 	}
+	card.Column_URL = column.URL
+	return nil
+}
 
-	newRepo, err := issue.GitHubClient.GetRepository(oldRepo.Owner.Login, repoName)
+// move moves the card to 'pos' in column 'columnID', 0 means its current
+// column
+func (card *Card) move(pos CardPosition, columnID int) error {
+	req := struct {
+		Position  CardPosition `json:"position"`
+		Column_ID int          `json:"column_id,omitempty"`
+	}{
+		Position:  pos,
+		Column_ID: columnID,
+	}
+	buf, err := json.Marshal(req)
 	if err != nil {
 		return err
 	}
-
-	cmd := fmt.Sprintf(`
-mutation {
-  transferIssue( input:{
-    issueId : "%s",
-	repositoryId : "%s"
-  }) {
-    issue { number }
-  }
+	_, err = card.Git("POST", card.URL+"/moves", string(buf))
+	return err
 }
-`, issue.Node_ID, newRepo.Node_ID)
 
-	res, err := issue.GraphQL(cmd)
+func (issue *Issue) MoveToRepository(repoName string) error {
+	oldRepo, err := issue.GetRepository()
 	if err != nil {
 		return err
 	}
 
-	if res["errors"] != nil {
-		return fmt.Errorf("GraphQL Error: %#v\n", res["errors"])
+	newRepo, err := issue.GitHubClient.GetRepository(oldRepo.Owner.Login, repoName)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return issue.Query(`
+mutation($issue: ID!, $repo: ID!) {
+  transferIssue(input: { issueId: $issue, repositoryId: $repo }) {
+    issue { number }
+  }
+}`, map[string]interface{}{
+		"issue": issue.Node_ID,
+		"repo":  newRepo.Node_ID,
+	}, nil)
 }
 
 func (project *Project) GetColumns() ([]*Column, error) {
@@ -1242,3 +1800,220 @@ func (column *Column) GetCards() ([]*Card, error) {
 
 	return cards, nil
 }
+
+// Pull Requests
+
+func (repo *Repository) GetPullRequests(query string) ([]*PullRequest, error) {
+	url := repo.URL + "/pulls"
+	if query != "" {
+		url += "?" + query
+	}
+
+	items, err := repo.GetAll(url, []*PullRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	prs := items.([]*PullRequest)
+	for _, pr := range prs {
+		pr.SetGH(repo.GitHubClient)
+	}
+
+	return prs, nil
+}
+
+func (repo *Repository) GetPullRequest(num int) (*PullRequest, error) {
+	return repo.GitHubClient.GetPullRequest(fmt.Sprintf("%s/pulls/%d",
+		repo.URL, num))
+}
+
+// /repos/:owner/:repo/pulls/:pull_number
+func (gh *GitHubClient) GetPullRequest(url string) (*PullRequest, error) {
+	res, err := gh.Git("GET", url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	pr := PullRequest{}
+	if err = json.Unmarshal(res.Body, &pr); err != nil {
+		return nil, err
+	}
+	pr.SetGH(gh)
+
+	return &pr, nil
+}
+
+func (gh *GitHubClient) GetPullRequestParts(org string, repo string, num int) (*PullRequest, error) {
+	url := fmt.Sprintf("/repos/%s/%s/pulls/%d", org, repo, num)
+	return gh.GetPullRequest(url)
+}
+
+func (issue *Issue) IsPullRequest() bool {
+	return issue.Pull_Request.URL != ""
+}
+
+func (issue *Issue) GetPullRequest() (*PullRequest, error) {
+	if !issue.IsPullRequest() {
+		return nil, fmt.Errorf("Issue #%d isn't a pull request", issue.Number)
+	}
+	return issue.GitHubClient.GetPullRequest(issue.Pull_Request.URL)
+}
+
+func (pr *PullRequest) Refresh() error {
+	newPR, err := pr.GetPullRequest(pr.URL)
+	if err != nil {
+		return err
+	}
+	*pr = PullRequest{}
+	*pr = *newPR
+	return nil
+}
+
+// GetIssue returns the Issue view of the PR, which is what's needed for
+// labels, comments and GitData
+func (pr *PullRequest) GetIssue() (*Issue, error) {
+	return pr.GitHubClient.GetIssue(pr.Issue_URL)
+}
+
+// Merge merges the PR using 'method' (merge, squash or rebase, "" means
+// merge). The merge fails if the head moved since the PR was fetched.
+func (pr *PullRequest) Merge(title string, message string, method string) (*MergeResult, error) {
+	req := struct {
+		Commit_Title   string `json:"commit_title,omitempty"`
+		Commit_Message string `json:"commit_message,omitempty"`
+		Merge_Method   string `json:"merge_method,omitempty"`
+		SHA            string `json:"sha,omitempty"`
+	}{
+		Commit_Title:   title,
+		Commit_Message: message,
+		Merge_Method:   method,
+	}
+	if pr.Head != nil {
+		req.SHA = pr.Head.SHA
+	}
+
+	buf, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := pr.Git("PUT", pr.URL+"/merge", string(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	result := MergeResult{}
+	if err = json.Unmarshal(res.Body, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (pr *PullRequest) RequestReviewers(users ...string) error {
+	return pr.reviewers("POST", users, nil)
+}
+
+func (pr *PullRequest) RequestTeamReviewers(teams ...string) error {
+	return pr.reviewers("POST", nil, teams)
+}
+
+func (pr *PullRequest) RemoveReviewers(users ...string) error {
+	return pr.reviewers("DELETE", users, nil)
+}
+
+func (pr *PullRequest) reviewers(method string, users []string, teams []string) error {
+	req := struct {
+		Reviewers      []string `json:"reviewers,omitempty"`
+		Team_Reviewers []string `json:"team_reviewers,omitempty"`
+	}{
+		Team_Reviewers: teams,
+	}
+	for _, user := range users {
+		if len(user) > 1 && user[0] == '@' {
+			user = user[1:]
+		}
+		req.Reviewers = append(req.Reviewers, user)
+	}
+
+	buf, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	_, err = pr.Git(method, pr.URL+"/requested_reviewers", string(buf))
+	return err
+}
+
+func (pr *PullRequest) IsRequestedReviewer(user string) bool {
+	if len(user) > 1 && user[0] == '@' {
+		user = user[1:]
+	}
+	for _, reviewer := range pr.Requested_Reviewers {
+		if strings.EqualFold(user, reviewer.Login) {
+			return true
+		}
+	}
+	return false
+}
+
+func (pr *PullRequest) GetFiles() ([]*PullRequestFile, error) {
+	items, err := pr.GetAll(pr.URL+"/files", []*PullRequestFile{})
+	if err != nil {
+		return nil, err
+	}
+	return items.([]*PullRequestFile), nil
+}
+
+func (pr *PullRequest) GetReviews() ([]*Review, error) {
+	items, err := pr.GetAll(pr.URL+"/reviews", []*Review{})
+	if err != nil {
+		return nil, err
+	}
+
+	reviews := items.([]*Review)
+	for _, review := range reviews {
+		review.SetGH(pr.GitHubClient)
+	}
+
+	return reviews, nil
+}
+
+func (pr *PullRequest) SetTitle(title string) error {
+	return pr.edit(map[string]interface{}{"title": title})
+}
+
+func (pr *PullRequest) SetBody(body string) error {
+	return pr.edit(map[string]interface{}{"body": body})
+}
+
+func (pr *PullRequest) Close() error {
+	return pr.edit(map[string]interface{}{"state": "closed"})
+}
+
+func (pr *PullRequest) Reopen() error {
+	return pr.edit(map[string]interface{}{"state": "open"})
+}
+
+func (pr *PullRequest) edit(fields map[string]interface{}) error {
+	buf, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	res, err := pr.Git("PATCH", pr.URL, string(buf))
+	if err != nil {
+		return err
+	}
+
+	newPR := PullRequest{}
+	if err = json.Unmarshal(res.Body, &newPR); err != nil {
+		return err
+	}
+	newPR.SetGH(pr.GitHubClient)
+
+	*pr = PullRequest{}
+	*pr = newPR
+
+	return nil
+}
@@ -0,0 +1,233 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeIssue is a GitHub server with a single issue (#1 in org/repo), its
+// comments and a "bot" user
+type fakeIssue struct {
+	mutex    sync.Mutex
+	host     string
+	body     string
+	updated  int
+	comments []*Comment
+	nextID   int
+	patches  int
+
+	// called for each GET of the issue, n counts from 1
+	onGet func(f *fakeIssue, n int)
+	gets  int
+}
+
+func newFakeIssue(t *testing.T, body string, opts ...ClientOption) (*fakeIssue, *Issue) {
+	f := &fakeIssue{body: body, nextID: 100}
+	gh := testClient(t, f.serve, opts...)
+	f.host = gh.Host
+
+	issue, err := gh.GetIssue("/repos/org/repo/issues/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.gets = 0
+	return f, issue
+}
+
+func (f *fakeIssue) issueURL() string {
+	return "https://" + f.host + "/api/v3/repos/org/repo/issues/1"
+}
+
+// change edits the body as someone else would
+func (f *fakeIssue) change(body string) {
+	f.body = body
+	f.updated++
+}
+
+func (f *fakeIssue) comment(user, body string) *Comment {
+	f.nextID++
+	f.updated++
+	c := &Comment{
+		ID:         f.nextID,
+		URL:        fmt.Sprintf("%s/comments/%d", f.issueURL(), f.nextID),
+		User:       &User{Login: user},
+		Body:       body,
+		Updated_At: fmt.Sprint(f.updated),
+	}
+	f.comments = append(f.comments, c)
+	return c
+}
+
+func (f *fakeIssue) serve(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	buf, _ := ioutil.ReadAll(r.Body)
+	req := struct{ Body string }{}
+	json.Unmarshal(buf, &req)
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v3/repos/org/repo/issues/1")
+	var res interface{}
+
+	switch {
+	case r.URL.Path == "/api/v3/user":
+		res = User{Login: "bot"}
+
+	case path == "" && r.Method == "GET":
+		f.gets++
+		if f.onGet != nil {
+			f.onGet(f, f.gets)
+		}
+
+	case path == "" && r.Method == "PATCH":
+		f.patches++
+		f.change(req.Body)
+
+	case path == "/comments" && r.Method == "GET":
+		res = f.comments
+
+	case path == "/comments" && r.Method == "POST":
+		res = f.comment("bot", req.Body)
+
+	case strings.HasPrefix(path, "/comments/"):
+		for i, c := range f.comments {
+			if !strings.HasSuffix(r.URL.Path, fmt.Sprintf("/%d", c.ID)) {
+				continue
+			}
+			switch r.Method {
+			case "PATCH":
+				f.updated++
+				c.Body, c.Updated_At = req.Body, fmt.Sprint(f.updated)
+			case "DELETE":
+				f.comments = append(f.comments[:i], f.comments[i+1:]...)
+				w.WriteHeader(204)
+				return
+			}
+			res = c
+		}
+		if res == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if res == nil {
+		res = map[string]interface{}{
+			"url":          f.issueURL(),
+			"comments_url": f.issueURL() + "/comments",
+			"html_url":     "https://github.com/org/repo/issues/1",
+			"number":       1,
+			"body":         f.body,
+			"updated_at":   fmt.Sprint(f.updated),
+		}
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+func TestUpdateRetries(t *testing.T) {
+	f, issue := newFakeIssue(t, "Text")
+
+	// Someone else adds a label between our read and our write
+	f.onGet = func(f *fakeIssue, n int) {
+		if n == 2 {
+			data := (&Issue{Body: f.body}).GetGitData()
+			data.AddData("Theirs", "x")
+			body, _ := data.body()
+			f.change(body)
+		}
+	}
+
+	calls := 0
+	_, err := issue.UpdateGitData(func(data *GitData) error {
+		calls++
+		data.AddData("Ours", "y")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || f.patches != 1 {
+		t.Errorf("got %d calls and %d writes, want 2 and 1", calls, f.patches)
+	}
+
+	data := (&Issue{Body: f.body}).GetGitData()
+	if !data.HasData("Theirs", "x") || !data.HasData("Ours", "y") {
+		t.Errorf("an update was lost:\n%s", f.body)
+	}
+	if issue.Body != f.body {
+		t.Errorf("issue wasn't refreshed")
+	}
+}
+
+func TestUpdateNoChange(t *testing.T) {
+	f, issue := newFakeIssue(t, "Text\n\n---\n**_Label_**: value\n")
+
+	// Legacy format isn't rewritten just to migrate it
+	_, err := issue.UpdateGitData(func(data *GitData) error {
+		data.AddData("Label", "value")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.patches != 0 || f.gets != 1 {
+		t.Errorf("got %d writes and %d reads, want 0 and 1", f.patches, f.gets)
+	}
+}
+
+func TestUpdateConflict(t *testing.T) {
+	f, issue := newFakeIssue(t, "Text")
+
+	// The issue changes between every read and write
+	f.onGet = func(f *fakeIssue, n int) {
+		if n%2 == 0 {
+			f.change(fmt.Sprintf("Text %d", n))
+		}
+	}
+
+	err := issue.AddData("Label", "value")
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("got %v, want ErrConflict", err)
+	}
+	if f.patches != 0 || f.gets != 2*updateTries {
+		t.Errorf("got %d writes and %d reads", f.patches, f.gets)
+	}
+}
+
+func TestSetGitDataConflict(t *testing.T) {
+	f, issue := newFakeIssue(t, "Text")
+
+	data := issue.GetGitData()
+	data.AddData("Label", "value")
+
+	f.change("Someone else's text")
+	if err := issue.SetGitData(data); !errors.Is(err, ErrConflict) {
+		t.Errorf("got %v, want ErrConflict", err)
+	}
+	if f.patches != 0 {
+		t.Errorf("stale data was written")
+	}
+
+	// Once refreshed it goes through
+	if err := issue.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	data = issue.GetGitData()
+	data.AddData("Label", "value")
+	if err := issue.SetGitData(data); err != nil {
+		t.Fatal(err)
+	}
+	if !issue.HasData("Label", "value") || f.patches != 1 {
+		t.Errorf("data wasn't written:\n%s", f.body)
+	}
+}
@@ -0,0 +1,185 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"strings"
+)
+
+// GraphQLError is one entry of the "errors" array of a GraphQL response
+type GraphQLError struct {
+	Type      string
+	Message   string
+	Path      []interface{}
+	Locations []struct {
+		Line   int
+		Column int
+	}
+}
+
+// GraphQLErrors is returned by Query when the response has an "errors"
+// array. Whatever "data" came back is still decoded.
+type GraphQLErrors []*GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	msgs := []string{}
+	for _, gqlErr := range e {
+		msg := gqlErr.Message
+		if gqlErr.Type != "" {
+			msg = gqlErr.Type + ": " + msg
+		}
+		msgs = append(msgs, msg)
+	}
+	return "GraphQL Error: " + strings.Join(msgs, "; ")
+}
+
+// Is lets errors.Is(err, ErrNotFound) work for GraphQL errors too
+func (e GraphQLErrors) Is(target error) bool {
+	for _, gqlErr := range e {
+		switch {
+		case target == ErrNotFound && gqlErr.Type == "NOT_FOUND",
+			target == ErrForbidden && gqlErr.Type == "FORBIDDEN",
+			target == ErrRateLimited && gqlErr.Type == "RATE_LIMITED":
+			return true
+		}
+	}
+	return false
+}
+
+// PageInfo is the "pageInfo { endCursor hasNextPage }" of a connection
+type PageInfo struct {
+	EndCursor   string
+	HasNextPage bool
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage
+	Errors GraphQLErrors
+}
+
+// graphQLURL is https://api.github.com/graphql for github.com and
+// https://HOST/api/graphql for GitHub Enterprise
+func (gh *GitHubClient) graphQLURL() string {
+	if gh.Host == "github.com" || gh.Host == "api.github.com" {
+		return "https://api.github.com/graphql"
+	}
+	return "https://" + gh.Host + "/api/graphql"
+}
+
+// graphQL goes through GitContext so it gets the same rate limit handling
+// as the REST calls. GitContext doesn't retry POSTs on transient errors,
+// and GraphQL reports its own rate limit with a 200, so those retries are
+// done here, see graphQLRetryWait.
+func (gh *GitHubClient) graphQL(ctx context.Context, query string, vars map[string]interface{}) ([]byte, error) {
+	js := struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables,omitempty"`
+	}{
+		Query:     query,
+		Variables: vars,
+	}
+
+	buf, err := json.Marshal(js)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := gh.retryPolicy()
+	mutation := strings.HasPrefix(strings.TrimSpace(query), "mutation")
+
+	for attempt := 0; ; attempt++ {
+		res, err := gh.GitContext(ctx, "POST", gh.graphQLURL(), string(buf))
+
+		wait, ok := policy.graphQLRetryWait(mutation, res, err, attempt)
+		if !ok {
+			if err != nil {
+				return nil, err
+			}
+			return res.Body, nil
+		}
+		log.Printf("GraphQL: retrying in %s", wait)
+		if err = sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Query runs a GraphQL query (or mutation) and decodes its "data" into
+// 'result', which can be nil if the data isn't needed:
+//
+//	result := struct {
+//		Repository struct {
+//			Issue struct{ ID string }
+//		}
+//	}{}
+//	err := gh.Query(`query($owner: String!, $name: String!, $num: Int!) {
+//	  repository(owner: $owner, name: $name) { issue(number: $num) { id } }
+//	}`, map[string]interface{}{"owner": "org", "name": "repo", "num": 1},
+//		&result)
+func (gh *GitHubClient) Query(query string, vars map[string]interface{}, result interface{}) error {
+	return gh.QueryContext(gh.context(), query, vars, result)
+}
+
+func (gh *GitHubClient) QueryContext(ctx context.Context, query string, vars map[string]interface{}, result interface{}) error {
+	buf, err := gh.graphQL(ctx, query, vars)
+	if err != nil {
+		return err
+	}
+
+	res := graphQLResponse{}
+	if err = json.Unmarshal(buf, &res); err != nil {
+		return err
+	}
+
+	if result != nil && len(res.Data) > 0 && string(res.Data) != "null" {
+		if err = json.Unmarshal(res.Data, result); err != nil {
+			return err
+		}
+	}
+
+	if len(res.Errors) > 0 {
+		return res.Errors
+	}
+	return nil
+}
+
+// QueryPages runs the query once per page. The query needs a "$cursor:
+// String" variable, which is set to the endCursor of the previous page.
+// 'result' is reset and each page is decoded into it, then 'page' is
+// called to copy what it needs from 'result'. It returns the PageInfo of
+// the connection being walked. To stop early return a PageInfo with
+// HasNextPage set to false.
+func (gh *GitHubClient) QueryPages(query string, vars map[string]interface{}, result interface{}, page func() (PageInfo, error)) error {
+	return gh.QueryPagesContext(gh.context(), query, vars, result, page)
+}
+
+func (gh *GitHubClient) QueryPagesContext(ctx context.Context, query string, vars map[string]interface{}, result interface{}, page func() (PageInfo, error)) error {
+	pageVars := map[string]interface{}{}
+	for k, v := range vars {
+		pageVars[k] = v
+	}
+
+	for {
+		// Start each page from scratch so nothing from the previous page
+		// (which the caller may have kept) is reused by json.Unmarshal
+		if result != nil {
+			val := reflect.ValueOf(result).Elem()
+			val.Set(reflect.Zero(val.Type()))
+		}
+
+		if err := gh.QueryContext(ctx, query, pageVars, result); err != nil {
+			return err
+		}
+
+		info, err := page()
+		if err != nil {
+			return err
+		}
+		if !info.HasNextPage || info.EndCursor == "" {
+			return nil
+		}
+		pageVars["cursor"] = info.EndCursor
+	}
+}
@@ -0,0 +1,110 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Pager walks a paginated list resource one page at a time, following
+// the "next" Link. Nothing is fetched until NextPage is called.
+type Pager struct {
+	*GitHubClient
+
+	ctx  context.Context
+	next string // URL of the next page, "" when there are no more
+}
+
+func (gh *GitHubClient) NewPager(url string) *Pager {
+	return gh.newPager(gh.context(), url)
+}
+
+func (gh *GitHubClient) newPager(ctx context.Context, url string) *Pager {
+	return &Pager{
+		GitHubClient: gh,
+		ctx:          ctx,
+		next:         url,
+	}
+}
+
+func (p *Pager) Done() bool {
+	return p.next == ""
+}
+
+// NextPage unmarshals the next page into 'items', a pointer to a slice
+func (p *Pager) NextPage(items interface{}) error {
+	if p.next == "" {
+		return fmt.Errorf("No more pages")
+	}
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := p.GitContext(p.ctx, "GET", p.next, "")
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(res.Body, items); err != nil {
+		return err
+	}
+
+	p.next = res.Links["next"]
+	return nil
+}
+
+// IssueIter returns the issues of a repo one at a time, fetching the next
+// page only when it's needed. Just stop calling Next to stop early:
+//
+//	it := repo.IssuesIter("state=open")
+//	for it.Next() {
+//		issue := it.Issue()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+type IssueIter struct {
+	pager *Pager
+	page  []*Issue
+	index int
+	issue *Issue
+	err   error
+}
+
+func (repo *Repository) IssuesIter(query string) *IssueIter {
+	url := repo.URL + "/issues"
+	if query != "" {
+		url += "?" + query
+	}
+	return &IssueIter{pager: repo.NewPager(url)}
+}
+
+func (it *IssueIter) Next() bool {
+	for it.index >= len(it.page) {
+		if it.err != nil || it.pager.Done() {
+			it.issue = nil
+			return false
+		}
+
+		page := []*Issue{}
+		if it.err = it.pager.NextPage(&page); it.err != nil {
+			it.issue = nil
+			return false
+		}
+		for _, issue := range page {
+			issue.SetGH(it.pager.GitHubClient)
+		}
+		it.page, it.index = page, 0
+	}
+
+	it.issue = it.page[it.index]
+	it.index++
+	return true
+}
+
+// Issue is the issue found by the last call to Next
+func (it *IssueIter) Issue() *Issue {
+	return it.issue
+}
+
+func (it *IssueIter) Err() error {
+	return it.err
+}
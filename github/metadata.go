@@ -0,0 +1,334 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MetadataVersion is the version of the metadata block that's written.
+// Blocks with a newer version are rejected rather than overwritten.
+const MetadataVersion = 1
+
+// The metadata block is an HTML comment at the end of the body, so it
+// isn't rendered:
+//
+//	<!-- metadata
+//	{
+//	  "version": 1,
+//	  "data": { "key": value, ... }
+//	}
+//	-->
+//
+// json.Marshal escapes '<' and '>' so no value can end the comment early.
+const metadataStart = "<!-- metadata"
+const metadataEnd = "-->"
+
+// Metadata is the structured data kept in an issue (or comment) body along
+// with the human authored text (Body). Values can be anything that can be
+// JSON encoded: strings, numbers, lists, maps, time.Time, structs...
+// The GitData funcs use the same block, their labels are the keys.
+// Reading a body in the old format ("**_Label_**: text" lines after a
+// "---") migrates it, even if there's a block too, and the next write uses
+// the new format.
+type Metadata struct {
+	Body    string // the text without the metadata
+	Version int
+	Values  map[string]json.RawMessage
+}
+
+type metadataBlock struct {
+	Version int                        `json:"version"`
+	Data    map[string]json.RawMessage `json:"data"`
+}
+
+func (issue *Issue) GetMetadata() (*Metadata, error) {
+	return ParseMetadata(issue.Body)
+}
+
+func (issue *Issue) SetMetadata(md *Metadata) error {
+	body, err := md.Encode()
+	if err != nil {
+		return err
+	}
+	if body == issue.Body {
+		return nil
+	}
+	return issue.SetBody(body)
+}
+
+// UpdateMetadata refetches the issue, calls 'fn' to change its Metadata
+// and writes it back, starting over if someone else changed the issue in
+// the meantime. See UpdateGitData.
+func (issue *Issue) UpdateMetadata(fn func(md *Metadata) error) (*Metadata, error) {
+	var md *Metadata
+	err := issue.update(func(cur *Issue) (string, bool, error) {
+		var err error
+		if md, err = cur.GetMetadata(); err != nil {
+			return "", false, err
+		}
+		orig, err := md.Encode()
+		if err != nil {
+			return "", false, err
+		}
+		if err = fn(md); err != nil {
+			return "", false, err
+		}
+		body, err := md.Encode()
+		if err != nil {
+			return "", false, err
+		}
+		return body, body != orig, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return md, nil
+}
+
+// ParseMetadata splits 'body' into its text and its metadata
+func ParseMetadata(body string) (*Metadata, error) {
+	md := &Metadata{
+		Version: MetadataVersion,
+		Values:  map[string]json.RawMessage{},
+	}
+
+	start := strings.LastIndex(body, metadataStart)
+	if start < 0 {
+		md.Body, md.Values = parseLegacyData(body)
+		return md, nil
+	}
+
+	end := strings.Index(body[start:], metadataEnd)
+	if end < 0 {
+		return nil, fmt.Errorf("Metadata block isn't terminated")
+	}
+	end += start
+
+	block := metadataBlock{}
+	buf := body[start+len(metadataStart) : end]
+	if err := json.Unmarshal([]byte(buf), &block); err != nil {
+		return nil, fmt.Errorf("Error parsing metadata: %s", err)
+	}
+	if block.Version > MetadataVersion {
+		return nil, fmt.Errorf("Metadata version %d is newer than %d",
+			block.Version, MetadataVersion)
+	}
+	if block.Data != nil {
+		md.Values = block.Data
+	}
+
+	// Lines in the old format after the block were added by older code,
+	// so they're newer than what's in the block
+	var legacy map[string]json.RawMessage
+	md.Body, legacy = parseLegacyData(body[:start] + body[end+len(metadataEnd):])
+	for key, value := range legacy {
+		md.Values[key] = value
+	}
+	return md, nil
+}
+
+// parseLegacyData pulls the "**_Label_**: text" lines out of the body.
+// Unlike ParseForGitData only the lines after the last "---" are used, and
+// only if that's all there is after it. Labels with one value become a
+// string, labels with more become a list.
+func parseLegacyData(body string) (string, map[string]json.RawMessage) {
+	values := map[string]json.RawMessage{}
+	body = strings.TrimRight(body, " \t\r\n")
+
+	lines := strings.Split(body, "\n")
+	sep := -1
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(lines[i]) == "---" {
+			sep = i
+			break
+		}
+	}
+	if sep < 0 {
+		return body, values
+	}
+
+	data := map[string][]string{}
+	for _, line := range lines[sep+1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		// **_Title_**: text
+		i := strings.Index(line, "_**: ")
+		if i < 2 || !strings.HasPrefix(line, "**_") {
+			return body, values // not a data section
+		}
+		label := strings.TrimSpace(line[3:i])
+		data[label] = append(data[label], strings.TrimSpace(line[i+5:]))
+	}
+	if len(data) == 0 {
+		return body, values
+	}
+
+	for label, texts := range data {
+		var buf []byte
+		if len(texts) == 1 {
+			buf, _ = json.Marshal(texts[0])
+		} else {
+			buf, _ = json.Marshal(texts)
+		}
+		values[label] = buf
+	}
+
+	return strings.TrimRight(strings.Join(lines[:sep], "\n"), " \t\r\n"),
+		values
+}
+
+// Encode returns the Body followed by the metadata block, if there are
+// any Values
+func (md *Metadata) Encode() (string, error) {
+	if len(md.Values) == 0 {
+		return md.Body, nil
+	}
+
+	buf, err := json.MarshalIndent(metadataBlock{
+		Version: MetadataVersion,
+		Data:    md.Values,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	body := md.Body
+	if body != "" {
+		body += "\n\n"
+	}
+	return body + metadataStart + "\n" + string(buf) + "\n" + metadataEnd + "\n",
+		nil
+}
+
+// Get decodes the value of 'key' into 'value' (a pointer). Returns false
+// if there's no such key.
+func (md *Metadata) Get(key string, value interface{}) (bool, error) {
+	buf, ok := md.Values[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(buf, value); err != nil {
+		return true, fmt.Errorf("Error decoding metadata %q: %s", key, err)
+	}
+	return true, nil
+}
+
+func (md *Metadata) Set(key string, value interface{}) error {
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("Error encoding metadata %q: %s", key, err)
+	}
+	if md.Values == nil {
+		md.Values = map[string]json.RawMessage{}
+	}
+	md.Values[key] = buf
+	return nil
+}
+
+// Delete returns false if the key wasn't there
+func (md *Metadata) Delete(key string) bool {
+	_, ok := md.Values[key]
+	delete(md.Values, key)
+	return ok
+}
+
+func (md *Metadata) Has(key string) bool {
+	_, ok := md.Values[key]
+	return ok
+}
+
+func (md *Metadata) Keys() []string {
+	keys := []string{}
+	for key := range md.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetString returns "" if the key is missing or isn't a string
+func (md *Metadata) GetString(key string) string {
+	str := ""
+	md.Get(key, &str)
+	return str
+}
+
+// GetStrings returns the list of strings for 'key'. A single string is
+// returned as a list of one, which is what migrated labels look like.
+func (md *Metadata) GetStrings(key string) []string {
+	buf, ok := md.Values[key]
+	if !ok {
+		return nil
+	}
+	list := []string{}
+	if bytes.HasPrefix(bytes.TrimSpace(buf), []byte("[")) {
+		if json.Unmarshal(buf, &list) == nil {
+			return list
+		}
+		return nil
+	}
+	if str := md.GetString(key); str != "" {
+		return []string{str}
+	}
+	return nil
+}
+
+// gitData returns the Metadata as GitData. Strings and lists of strings
+// become one entry per string, other values are kept aside as is.
+func (md *Metadata) gitData() *GitData {
+	data := &GitData{}
+	if md.Body != "" {
+		data.Body = strings.Split(md.Body, "\n")
+	}
+
+	for _, key := range md.Keys() {
+		buf := md.Values[key]
+		text, texts := "", []string{}
+		if json.Unmarshal(buf, &text) == nil {
+			data.AddData(key, text)
+		} else if json.Unmarshal(buf, &texts) == nil {
+			for _, text := range texts {
+				data.AddData(key, text)
+			}
+		} else {
+			if data.other == nil {
+				data.other = map[string]json.RawMessage{}
+			}
+			data.other[key] = buf
+		}
+	}
+	return data
+}
+
+// metadata is the reverse of gitData, a label with one text is saved as a
+// string and one with more as a list
+func (data *GitData) metadata() *Metadata {
+	md := &Metadata{
+		Version: MetadataVersion,
+		Values:  map[string]json.RawMessage{},
+		Body:    strings.TrimRight(strings.Join(data.Body, "\n"), " \t\r\n"),
+	}
+	for key, value := range data.other {
+		md.Values[key] = value
+	}
+
+	entries := append([][2]string{}, data.Data...)
+	sort.Sort(ByLabel(entries))
+	texts := map[string][]string{}
+	for _, entry := range entries {
+		texts[entry[0]] = append(texts[entry[0]], entry[1])
+	}
+	for label, list := range texts {
+		if len(list) == 1 {
+			md.Set(label, list[0])
+		} else {
+			md.Set(label, list)
+		}
+	}
+	return md
+}
@@ -0,0 +1,186 @@
+package github
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	type point struct{ X, Y int }
+
+	md := &Metadata{Body: "Some text\n\n---\nmore text"}
+	md.Set("multi", "line one\nline two\n--> not the end")
+	md.Set("list", []string{"a", "b"})
+	md.Set("number", 42)
+	md.Set("point", point{1, 2})
+
+	body, err := md.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(body, metadataEnd) != 1 {
+		t.Fatalf("value ended the block early:\n%s", body)
+	}
+
+	md, err = ParseMetadata(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Body != "Some text\n\n---\nmore text" {
+		t.Errorf("bad body: %q", md.Body)
+	}
+	if got := md.GetString("multi"); got != "line one\nline two\n--> not the end" {
+		t.Errorf("bad multi-line value: %q", got)
+	}
+	if got := md.GetStrings("list"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("bad list: %q", got)
+	}
+	num, p := 0, point{}
+	if ok, err := md.Get("number", &num); !ok || err != nil || num != 42 {
+		t.Errorf("bad number: %d %v %v", num, ok, err)
+	}
+	if ok, err := md.Get("point", &p); !ok || err != nil || p != (point{1, 2}) {
+		t.Errorf("bad point: %v %v %v", p, ok, err)
+	}
+	if ok, _ := md.Get("missing", &num); ok {
+		t.Errorf("missing key was found")
+	}
+
+	if !md.Delete("number") || md.Delete("number") || md.Has("number") {
+		t.Errorf("delete didn't work")
+	}
+
+	// No values, no block
+	if body, _ = (&Metadata{Body: "text"}).Encode(); body != "text" {
+		t.Errorf("empty metadata added a block: %q", body)
+	}
+}
+
+func TestParseMetadataErrors(t *testing.T) {
+	bodies := []string{
+		"text\n<!-- metadata\n{\"version\": 1}\n",
+		"text\n<!-- metadata\nnot json\n-->\n",
+		"text\n<!-- metadata\n{\"version\": 99, \"data\": {}}\n-->\n",
+	}
+	for _, body := range bodies {
+		if _, err := ParseMetadata(body); err == nil {
+			t.Errorf("no error for %q", body)
+		}
+	}
+}
+
+func TestParseLegacyMetadata(t *testing.T) {
+	body := "Description\n\n---\nnot data\n\n---\n" +
+		"**_Aha_**: APP-1\n" +
+		"**_Requirement_**: APP-1-1 #2\n" +
+		"**_Requirement_**: APP-1-2 #3\n"
+
+	md, err := ParseMetadata(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Body != "Description\n\n---\nnot data" {
+		t.Errorf("bad body: %q", md.Body)
+	}
+	if got := md.GetString("Aha"); got != "APP-1" {
+		t.Errorf("bad Aha: %q", got)
+	}
+	want := []string{"APP-1-1 #2", "APP-1-2 #3"}
+	if got := md.GetStrings("Requirement"); !reflect.DeepEqual(got, want) {
+		t.Errorf("bad Requirement: %q", got)
+	}
+
+	// The next write uses the block
+	body, _ = md.Encode()
+	if strings.Contains(body, "**_") || !strings.Contains(body, metadataStart) {
+		t.Errorf("not migrated:\n%s", body)
+	}
+
+	// A "---" that isn't followed by only data lines is just text
+	text := "Intro\n\n---\n**_Bold_**: yes\nand more text"
+	if md, _ = ParseMetadata(text); md.Body != text || len(md.Values) != 0 {
+		t.Errorf("text was parsed as data: %q %v", md.Body, md.Values)
+	}
+}
+
+func TestParseLegacyMetadataWithBlock(t *testing.T) {
+	md := &Metadata{Body: "Description"}
+	md.Set("Aha", "APP-1")
+	md.Set("Note", "first\nsecond")
+	md.Set("Count", 3)
+	body, _ := md.Encode()
+
+	// Older code appended lines after the block
+	body += "\n---\n**_Aha_**: APP-2\n**_Status_**: open\n"
+
+	md, err := ParseMetadata(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Body != "Description" {
+		t.Errorf("bad body: %q", md.Body)
+	}
+	if got := md.GetString("Aha"); got != "APP-2" {
+		t.Errorf("legacy line didn't win: %q", got)
+	}
+	if got := md.GetString("Status"); got != "open" {
+		t.Errorf("legacy line lost: %q", got)
+	}
+	if got := md.GetString("Note"); got != "first\nsecond" {
+		t.Errorf("multi-line value lost: %q", got)
+	}
+	count := 0
+	if md.Get("Count", &count); count != 3 {
+		t.Errorf("typed value lost: %d", count)
+	}
+}
+
+func TestGitDataMetadata(t *testing.T) {
+	md := &Metadata{Body: "line 1\nline 2"}
+	md.Set("One", "a\nb")
+	md.Set("Two", []string{"x", "y"})
+	md.Set("Typed", map[string]int{"n": 1})
+	body, _ := md.Encode()
+
+	issue := &Issue{Body: body}
+	data := issue.GetGitData()
+	if !reflect.DeepEqual(data.Body, []string{"line 1", "line 2"}) {
+		t.Errorf("bad body: %q", data.Body)
+	}
+	if !data.HasData("One", "a\nb") {
+		t.Errorf("bad One: %q", data.Data)
+	}
+	if !data.HasData("Two", "x") || !data.HasData("Two", "y") {
+		t.Errorf("bad Two: %q", data.Data)
+	}
+
+	// Writing it back keeps what GitData doesn't understand
+	data.AddData("Three", "z")
+	data.DeleteData("Two", "x")
+	newBody, err := data.body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, _ = ParseMetadata(newBody)
+	if got := md.GetString("One"); got != "a\nb" {
+		t.Errorf("multi-line value lost: %q", got)
+	}
+	if got := md.GetString("Two"); got != "y" {
+		t.Errorf("bad Two: %q", got)
+	}
+	if got := md.GetString("Three"); got != "z" {
+		t.Errorf("bad Three: %q", got)
+	}
+	typed := map[string]int{}
+	if md.Get("Typed", &typed); typed["n"] != 1 {
+		t.Errorf("typed value lost: %v", md.Values["Typed"])
+	}
+
+	// Deleting all of a label removes typed values too
+	data.DeleteData("Typed", "")
+	newBody, _ = data.body()
+	if md, _ = ParseMetadata(newBody); md.Has("Typed") {
+		t.Errorf("typed value wasn't deleted")
+	}
+}
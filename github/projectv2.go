@@ -0,0 +1,473 @@
+package github
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const projectV2Fields = `id number title url closed`
+
+// GetOrgProjectsV2 returns all of the ProjectV2s owned by the org
+func (gh *GitHubClient) GetOrgProjectsV2(org string) ([]*ProjectV2, error) {
+	return gh.getProjectsV2("organization", org)
+}
+
+// GetUserProjectsV2 returns all of the ProjectV2s owned by the user
+func (gh *GitHubClient) GetUserProjectsV2(login string) ([]*ProjectV2, error) {
+	return gh.getProjectsV2("user", login)
+}
+
+// ownerType is "organization" or "user"
+func (gh *GitHubClient) getProjectsV2(ownerType string, login string) ([]*ProjectV2, error) {
+	query := fmt.Sprintf(`
+query($login: String!, $cursor: String) {
+  owner: %s(login: $login) {
+    projectsV2(first: 100, after: $cursor) {
+      nodes { %s }
+      pageInfo { endCursor hasNextPage }
+    }
+  }
+}`, ownerType, projectV2Fields)
+
+	result := struct {
+		Owner struct {
+			ProjectsV2 struct {
+				Nodes    []*ProjectV2
+				PageInfo PageInfo
+			}
+		}
+	}{}
+
+	projects := []*ProjectV2{}
+	err := gh.QueryPages(query, map[string]interface{}{"login": login},
+		&result, func() (PageInfo, error) {
+			for _, project := range result.Owner.ProjectsV2.Nodes {
+				project.GitHubClient = gh
+				project.Owner = login
+				projects = append(projects, project)
+			}
+			return result.Owner.ProjectsV2.PageInfo, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (gh *GitHubClient) GetOrgProjectV2(org string, number int) (*ProjectV2, error) {
+	return gh.getProjectV2("organization", org, number)
+}
+
+func (gh *GitHubClient) GetUserProjectV2(login string, number int) (*ProjectV2, error) {
+	return gh.getProjectV2("user", login, number)
+}
+
+func (gh *GitHubClient) getProjectV2(ownerType string, login string, number int) (*ProjectV2, error) {
+	query := fmt.Sprintf(`
+query($login: String!, $number: Int!) {
+  owner: %s(login: $login) {
+    projectV2(number: $number) { %s }
+  }
+}`, ownerType, projectV2Fields)
+
+	result := struct {
+		Owner struct {
+			ProjectV2 *ProjectV2
+		}
+	}{}
+
+	err := gh.Query(query, map[string]interface{}{
+		"login":  login,
+		"number": number,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	project := result.Owner.ProjectV2
+	if project == nil {
+		return nil, fmt.Errorf("Can't find project %d of %q", number, login)
+	}
+	project.GitHubClient = gh
+	project.Owner = login
+	return project, nil
+}
+
+// GetOrgProjectV2ByTitle returns nil if there's no project with that title
+func (gh *GitHubClient) GetOrgProjectV2ByTitle(org string, title string) (*ProjectV2, error) {
+	projects, err := gh.GetOrgProjectsV2(org)
+	if err != nil {
+		return nil, err
+	}
+	for _, project := range projects {
+		if project.Title == title {
+			return project, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetFields loads (and saves in project.Fields) all of the project's
+// fields along with their options and iterations
+func (project *ProjectV2) GetFields() ([]*ProjectV2Field, error) {
+	query := `
+query($id: ID!, $cursor: String) {
+  node(id: $id) {
+    ... on ProjectV2 {
+      fields(first: 100, after: $cursor) {
+        nodes {
+          ... on ProjectV2FieldCommon { id name dataType }
+          ... on ProjectV2SingleSelectField { options { id name } }
+          ... on ProjectV2IterationField {
+            configuration {
+              iterations { id title startDate duration }
+              completedIterations { id title startDate duration }
+            }
+          }
+        }
+        pageInfo { endCursor hasNextPage }
+      }
+    }
+  }
+}`
+
+	result := struct {
+		Node struct {
+			Fields struct {
+				Nodes []struct {
+					ID            string
+					Name          string
+					DataType      string
+					Options       []*ProjectV2Option
+					Configuration struct {
+						Iterations          []*ProjectV2Iteration
+						CompletedIterations []*ProjectV2Iteration
+					}
+				}
+				PageInfo PageInfo
+			}
+		}
+	}{}
+
+	fields := []*ProjectV2Field{}
+	err := project.QueryPages(query, map[string]interface{}{"id": project.ID},
+		&result, func() (PageInfo, error) {
+			for _, node := range result.Node.Fields.Nodes {
+				fields = append(fields, &ProjectV2Field{
+					ID:       node.ID,
+					Name:     node.Name,
+					DataType: node.DataType,
+					Options:  node.Options,
+					Iterations: append(node.Configuration.Iterations,
+						node.Configuration.CompletedIterations...),
+				})
+			}
+			return result.Node.Fields.PageInfo, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	project.Fields = fields
+	return fields, nil
+}
+
+// GetField returns the field called 'name', loading the fields first if
+// needed
+func (project *ProjectV2) GetField(name string) (*ProjectV2Field, error) {
+	if project.Fields == nil {
+		if _, err := project.GetFields(); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range project.Fields {
+		if field.Name == name {
+			return field, nil
+		}
+	}
+	return nil, fmt.Errorf("Can't find field %q in project %q", name,
+		project.Title)
+}
+
+// GetItems returns all items in the project with their field values
+func (project *ProjectV2) GetItems() ([]*ProjectV2Item, error) {
+	query := `
+query($id: ID!, $cursor: String) {
+  node(id: $id) {
+    ... on ProjectV2 {
+      items(first: 100, after: $cursor) {
+        nodes {
+          id
+          type
+          content {
+            ... on Issue { number title url }
+            ... on PullRequest { number title url }
+            ... on DraftIssue { title }
+          }
+          fieldValues(first: 50) {
+            nodes {
+              ... on ProjectV2ItemFieldTextValue {
+                text field { ... on ProjectV2FieldCommon { name } }
+              }
+              ... on ProjectV2ItemFieldNumberValue {
+                number field { ... on ProjectV2FieldCommon { name } }
+              }
+              ... on ProjectV2ItemFieldDateValue {
+                date field { ... on ProjectV2FieldCommon { name } }
+              }
+              ... on ProjectV2ItemFieldSingleSelectValue {
+                name field { ... on ProjectV2FieldCommon { name } }
+              }
+              ... on ProjectV2ItemFieldIterationValue {
+                title field { ... on ProjectV2FieldCommon { name } }
+              }
+            }
+          }
+        }
+        pageInfo { endCursor hasNextPage }
+      }
+    }
+  }
+}`
+
+	type fieldValue struct {
+		Text   *string
+		Number *float64
+		Date   *string
+		Name   *string
+		Title  *string
+		Field  struct {
+			Name string
+		}
+	}
+
+	result := struct {
+		Node struct {
+			Items struct {
+				Nodes []struct {
+					ID      string
+					Type    string
+					Content struct {
+						Number int
+						Title  string
+						URL    string
+					}
+					FieldValues struct {
+						Nodes []fieldValue
+					}
+				}
+				PageInfo PageInfo
+			}
+		}
+	}{}
+
+	items := []*ProjectV2Item{}
+	err := project.QueryPages(query, map[string]interface{}{"id": project.ID},
+		&result, func() (PageInfo, error) {
+			for _, node := range result.Node.Items.Nodes {
+				item := &ProjectV2Item{
+					GitHubClient: project.GitHubClient,
+					Project:      project,
+					ID:           node.ID,
+					Type:         node.Type,
+					Number:       node.Content.Number,
+					Title:        node.Content.Title,
+					URL:          node.Content.URL,
+					Values:       map[string]string{},
+				}
+				for _, fv := range node.FieldValues.Nodes {
+					if fv.Field.Name == "" {
+						continue // a type of value we didn't ask for
+					}
+					switch {
+					case fv.Text != nil:
+						item.Values[fv.Field.Name] = *fv.Text
+					case fv.Number != nil:
+						item.Values[fv.Field.Name] =
+							strconv.FormatFloat(*fv.Number, 'f', -1, 64)
+					case fv.Date != nil:
+						item.Values[fv.Field.Name] = *fv.Date
+					case fv.Name != nil:
+						item.Values[fv.Field.Name] = *fv.Name
+					case fv.Title != nil:
+						item.Values[fv.Field.Name] = *fv.Title
+					}
+				}
+				items = append(items, item)
+			}
+			return result.Node.Items.PageInfo, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetItemsByField returns the items whose 'field' is 'value', e.g.
+// ("Status", "In Progress"). A value of "" finds items with no value.
+func (project *ProjectV2) GetItemsByField(field string, value string) ([]*ProjectV2Item, error) {
+	items, err := project.GetItems()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*ProjectV2Item{}
+	for _, item := range items {
+		if item.Values[field] == value {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// AddItem adds the issue or PR with node ID 'contentID' to the project.
+// If it's already there the existing item is returned.
+func (project *ProjectV2) AddItem(contentID string) (*ProjectV2Item, error) {
+	result := struct {
+		AddProjectV2ItemById struct {
+			Item struct {
+				ID   string
+				Type string
+			}
+		}
+	}{}
+
+	err := project.Query(`
+mutation($project: ID!, $content: ID!) {
+  addProjectV2ItemById(input: { projectId: $project, contentId: $content }) {
+    item { id type }
+  }
+}`, map[string]interface{}{
+		"project": project.ID,
+		"content": contentID,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProjectV2Item{
+		GitHubClient: project.GitHubClient,
+		Project:      project,
+		ID:           result.AddProjectV2ItemById.Item.ID,
+		Type:         result.AddProjectV2ItemById.Item.Type,
+		Values:       map[string]string{},
+	}, nil
+}
+
+func (issue *Issue) AddToProjectV2(project *ProjectV2) (*ProjectV2Item, error) {
+	item, err := project.AddItem(issue.Node_ID)
+	if err == nil {
+		item.Number, item.Title, item.URL = issue.Number, issue.Title, issue.HTML_URL
+	}
+	return item, err
+}
+
+func (pr *PullRequest) AddToProjectV2(project *ProjectV2) (*ProjectV2Item, error) {
+	item, err := project.AddItem(pr.Node_ID)
+	if err == nil {
+		item.Number, item.Title, item.URL = pr.Number, pr.Title, pr.HTML_URL
+	}
+	return item, err
+}
+
+// SetField sets the item's field. 'value' is the option name for single
+// select fields, the iteration title for iteration fields, a number for
+// number fields and YYYY-MM-DD for date fields.
+func (item *ProjectV2Item) SetField(name string, value string) error {
+	field, err := item.Project.GetField(name)
+	if err != nil {
+		return err
+	}
+
+	var fieldValue map[string]interface{}
+
+	switch field.DataType {
+	case "SINGLE_SELECT":
+		for _, opt := range field.Options {
+			if opt.Name == value {
+				fieldValue = map[string]interface{}{"singleSelectOptionId": opt.ID}
+			}
+		}
+	case "ITERATION":
+		for _, iter := range field.Iterations {
+			if iter.Title == value {
+				fieldValue = map[string]interface{}{"iterationId": iter.ID}
+			}
+		}
+	case "NUMBER":
+		num, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("Field %q needs a number: %s", name, err)
+		}
+		fieldValue = map[string]interface{}{"number": num}
+	case "DATE":
+		fieldValue = map[string]interface{}{"date": value}
+	case "TEXT":
+		fieldValue = map[string]interface{}{"text": value}
+	default:
+		return fmt.Errorf("Can't set field %q of type %s", name, field.DataType)
+	}
+
+	if fieldValue == nil {
+		return fmt.Errorf("Field %q has no %q", name, value)
+	}
+
+	err = item.Query(`
+mutation($project: ID!, $item: ID!, $field: ID!, $value: ProjectV2FieldValue!) {
+  updateProjectV2ItemFieldValue(input: {
+    projectId: $project, itemId: $item, fieldId: $field, value: $value
+  }) {
+    projectV2Item { id }
+  }
+}`, map[string]interface{}{
+		"project": item.Project.ID,
+		"item":    item.ID,
+		"field":   field.ID,
+		"value":   fieldValue,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	item.Values[name] = value
+	return nil
+}
+
+func (item *ProjectV2Item) ClearField(name string) error {
+	field, err := item.Project.GetField(name)
+	if err != nil {
+		return err
+	}
+
+	err = item.Query(`
+mutation($project: ID!, $item: ID!, $field: ID!) {
+  clearProjectV2ItemFieldValue(input: {
+    projectId: $project, itemId: $item, fieldId: $field
+  }) {
+    projectV2Item { id }
+  }
+}`, map[string]interface{}{
+		"project": item.Project.ID,
+		"item":    item.ID,
+		"field":   field.ID,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	delete(item.Values, name)
+	return nil
+}
+
+// Delete removes the item from its project, the issue/PR isn't touched
+func (item *ProjectV2Item) Delete() error {
+	return item.Query(`
+mutation($project: ID!, $item: ID!) {
+  deleteProjectV2Item(input: { projectId: $project, itemId: $item }) {
+    deletedItemId
+  }
+}`, map[string]interface{}{
+		"project": item.Project.ID,
+		"item":    item.ID,
+	}, nil)
+}
@@ -0,0 +1,207 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit is the state of the caller's rate limit as reported by the
+// X-RateLimit-* headers of the most recent response
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Used      int
+	Reset     time.Time
+	Resource  string
+}
+
+// RetryPolicy controls how GitHubClient.Git retries requests that were
+// rate limited or failed with a transient (502/503/504) error.
+// Rate limited requests are retried for any method since GitHub never
+// processed them, transient errors only for idempotent methods.
+type RetryPolicy struct {
+	MaxRetries int           // 0 means never retry
+	MaxWait    time.Duration // give up if we'd need to sleep longer than this
+	MinBackoff time.Duration // first backoff when there's no Retry-After
+	MaxBackoff time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	MaxWait:    2 * time.Minute,
+	MinBackoff: 1 * time.Second,
+	MaxBackoff: 30 * time.Second,
+}
+
+// NoRetryPolicy turns off all retries, errors are returned right away
+var NoRetryPolicy = RetryPolicy{}
+
+// WithRetryPolicy replaces DefaultRetryPolicy for this client
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.retry = &policy
+	}
+}
+
+func (gh *GitHubClient) retryPolicy() *RetryPolicy {
+	if gh.Retry != nil {
+		return gh.Retry
+	}
+	return &DefaultRetryPolicy
+}
+
+func parseRateLimit(header http.Header) RateLimit {
+	rl := RateLimit{
+		Limit:     -1,
+		Remaining: -1,
+		Used:      -1,
+		Resource:  header.Get("X-RateLimit-Resource"),
+	}
+
+	if v, err := strconv.Atoi(header.Get("X-RateLimit-Limit")); err == nil {
+		rl.Limit = v
+	}
+	if v, err := strconv.Atoi(header.Get("X-RateLimit-Remaining")); err == nil {
+		rl.Remaining = v
+	}
+	if v, err := strconv.Atoi(header.Get("X-RateLimit-Used")); err == nil {
+		rl.Used = v
+	}
+	if v, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rl.Reset = time.Unix(v, 0)
+	}
+
+	return rl
+}
+
+func isIdempotent(method string) bool {
+	switch strings.ToUpper(method) {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// isRateLimited checks for both the primary (Remaining == 0) and the
+// secondary/abuse rate limits. Both use 403 or 429.
+func isRateLimited(res *http.Response, rl RateLimit, body []byte) bool {
+	if res.StatusCode != 403 && res.StatusCode != 429 {
+		return false
+	}
+	if rl.Remaining == 0 || res.Header.Get("Retry-After") != "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(string(body)), "rate limit")
+}
+
+// backoff returns how long to wait before retry number 'attempt' (0 based)
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	wait := policy.MinBackoff
+	if wait <= 0 {
+		wait = time.Second
+	}
+	for i := 0; i < attempt; i++ {
+		wait *= 2
+		if policy.MaxBackoff > 0 && wait >= policy.MaxBackoff {
+			return policy.MaxBackoff
+		}
+	}
+	return wait
+}
+
+// retryWait decides whether the failed request should be retried and, if
+// so, for how long to sleep first. res is nil for network errors.
+func (policy *RetryPolicy) retryWait(method string, res *http.Response, rl RateLimit, body []byte, attempt int) (time.Duration, bool) {
+	if attempt >= policy.MaxRetries {
+		return 0, false
+	}
+
+	wait := time.Duration(0)
+
+	switch {
+	case res == nil:
+		if !isIdempotent(method) {
+			return 0, false
+		}
+		wait = policy.backoff(attempt)
+
+	case isRateLimited(res, rl, body):
+		if after := res.Header.Get("Retry-After"); after != "" {
+			if secs, err := strconv.Atoi(after); err == nil {
+				wait = time.Duration(secs) * time.Second
+			} else if t, err := http.ParseTime(after); err == nil {
+				wait = time.Until(t)
+			}
+		} else if rl.Remaining == 0 && !rl.Reset.IsZero() {
+			wait = time.Until(rl.Reset) + time.Second // allow for clock skew
+		} else {
+			// Secondary limits w/o Retry-After, GitHub says wait a minute
+			wait = time.Minute
+		}
+
+	case res.StatusCode == 502 || res.StatusCode == 503 || res.StatusCode == 504:
+		if !isIdempotent(method) {
+			return 0, false
+		}
+		wait = policy.backoff(attempt)
+
+	default:
+		return 0, false
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+	if policy.MaxWait > 0 && wait > policy.MaxWait {
+		return 0, false
+	}
+	return wait, true
+}
+
+// graphQLRetryWait is retryWait for GraphQL calls, which are all POSTs so
+// Git never retries their transient errors. Queries are retried on a
+// 502/503/504 but mutations aren't, they may have been applied. A
+// RATE_LIMITED error comes back as a 200 and is retried for both.
+func (policy *RetryPolicy) graphQLRetryWait(mutation bool, res *GitResponse, err error, attempt int) (time.Duration, bool) {
+	if attempt >= policy.MaxRetries {
+		return 0, false
+	}
+
+	wait := time.Duration(0)
+
+	if err != nil {
+		apiErr := &APIError{}
+		if mutation || !errors.As(err, &apiErr) {
+			return 0, false
+		}
+		switch apiErr.StatusCode {
+		case 502, 503, 504:
+			wait = policy.backoff(attempt)
+		default:
+			return 0, false
+		}
+	} else {
+		gqlRes := graphQLResponse{}
+		if json.Unmarshal(res.Body, &gqlRes) != nil ||
+			!errors.Is(gqlRes.Errors, ErrRateLimited) {
+			return 0, false
+		}
+		if res.RateLimit.Remaining == 0 && !res.RateLimit.Reset.IsZero() {
+			wait = time.Until(res.RateLimit.Reset) + time.Second
+		} else {
+			wait = time.Minute
+		}
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+	if policy.MaxWait > 0 && wait > policy.MaxWait {
+		return 0, false
+	}
+	return wait, true
+}
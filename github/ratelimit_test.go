@@ -0,0 +1,180 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClient returns a client whose requests all go to 'handler'
+func testClient(t *testing.T, handler http.HandlerFunc, opts ...ClientOption) *GitHubClient {
+	t.Helper()
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+
+	opts = append([]ClientOption{WithHTTPClient(srv.Client())}, opts...)
+	gh, err := NewGitHubClientE(srv.Listener.Addr().String(), "token", "",
+		opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gh
+}
+
+var testPolicy = RetryPolicy{
+	MaxRetries: 3,
+	MaxWait:    time.Minute,
+	MinBackoff: time.Millisecond,
+	MaxBackoff: 4 * time.Millisecond,
+}
+
+func TestBackoff(t *testing.T) {
+	want := []time.Duration{1, 2, 4, 4, 4}
+	for attempt, w := range want {
+		if got := testPolicy.backoff(attempt); got != w*time.Millisecond {
+			t.Errorf("backoff(%d): got %s, want %s", attempt, got,
+				w*time.Millisecond)
+		}
+	}
+
+	if got := (&RetryPolicy{}).backoff(0); got != time.Second {
+		t.Errorf("default backoff: got %s, want 1s", got)
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	response := func(code int, header ...string) *http.Response {
+		res := &http.Response{StatusCode: code, Header: http.Header{}}
+		for i := 0; i+1 < len(header); i += 2 {
+			res.Header.Set(header[i], header[i+1])
+		}
+		return res
+	}
+	noLimit := RateLimit{Remaining: -1}
+
+	tests := []struct {
+		name    string
+		method  string
+		res     *http.Response
+		rl      RateLimit
+		body    string
+		attempt int
+		wait    time.Duration
+		retry   bool
+	}{
+		{"network error GET", "GET", nil, noLimit, "", 0, time.Millisecond, true},
+		{"network error POST", "POST", nil, noLimit, "", 0, 0, false},
+		{"503 GET", "GET", response(503), noLimit, "", 1, 2 * time.Millisecond, true},
+		{"502 PUT", "PUT", response(502), noLimit, "", 0, time.Millisecond, true},
+		{"504 PATCH", "PATCH", response(504), noLimit, "", 0, 0, false},
+		{"500", "GET", response(500), noLimit, "", 0, 0, false},
+		{"404", "GET", response(404), noLimit, "", 0, 0, false},
+		{"out of retries", "GET", response(503), noLimit, "", 3, 0, false},
+		{"Retry-After secs", "POST", response(429, "Retry-After", "7"),
+			noLimit, "", 0, 7 * time.Second, true},
+		{"Retry-After too long", "GET", response(403, "Retry-After", "120"),
+			noLimit, "", 0, 0, false},
+		{"secondary limit", "POST", response(403), noLimit,
+			`{"message":"You have exceeded a secondary rate limit"}`, 0,
+			time.Minute, true},
+		{"plain 403", "GET", response(403), noLimit,
+			`{"message":"Resource not accessible"}`, 0, 0, false},
+	}
+
+	for _, test := range tests {
+		wait, retry := testPolicy.retryWait(test.method, test.res, test.rl,
+			[]byte(test.body), test.attempt)
+		if retry != test.retry || wait != test.wait {
+			t.Errorf("%s: got %s/%v, want %s/%v", test.name, wait, retry,
+				test.wait, test.retry)
+		}
+	}
+}
+
+func TestRetryWaitDates(t *testing.T) {
+	// Retry-After as an HTTP date
+	res := &http.Response{StatusCode: 429, Header: http.Header{}}
+	res.Header.Set("Retry-After",
+		time.Now().Add(30*time.Second).UTC().Format(http.TimeFormat))
+	wait, ok := testPolicy.retryWait("GET", res, RateLimit{Remaining: -1},
+		nil, 0)
+	if !ok || wait <= 25*time.Second || wait > 30*time.Second {
+		t.Errorf("Retry-After date: got %s/%v", wait, ok)
+	}
+
+	// Primary limit, wait for the reset
+	res = &http.Response{StatusCode: 403, Header: http.Header{}}
+	rl := RateLimit{Remaining: 0, Reset: time.Now().Add(10 * time.Second)}
+	wait, ok = testPolicy.retryWait("POST", res, rl, nil, 0)
+	if !ok || wait <= 9*time.Second || wait > 11*time.Second {
+		t.Errorf("X-RateLimit-Reset: got %s/%v", wait, ok)
+	}
+
+	// A reset in the past means go right away
+	rl.Reset = time.Now().Add(-time.Hour)
+	wait, ok = testPolicy.retryWait("GET", res, rl, nil, 0)
+	if !ok || wait != 0 {
+		t.Errorf("past reset: got %s/%v", wait, ok)
+	}
+}
+
+func TestGitRetries(t *testing.T) {
+	calls := int32(0)
+	gh := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(503)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(429)
+		default:
+			w.Header().Set("X-RateLimit-Remaining", "42")
+			w.Write([]byte(`{"number":1}`))
+		}
+	}, WithRetryPolicy(testPolicy))
+
+	res, err := gh.Git("GET", "/repos/org/repo/issues/1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, want 3", calls)
+	}
+	if res.RateLimit.Remaining != 42 || string(res.Body) != `{"number":1}` {
+		t.Errorf("bad response: %+v", res)
+	}
+}
+
+func TestGitNoRetry(t *testing.T) {
+	calls := int32(0)
+	gh := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(503)
+		w.Write([]byte(`{"message":"unavailable"}`))
+	}, WithRetryPolicy(testPolicy))
+
+	// Transient errors aren't retried for POSTs
+	_, err := gh.Git("POST", "/repos/org/repo/issues", "{}")
+	if !errors.Is(err, ErrServer) {
+		t.Errorf("got %v, want ErrServer", err)
+	}
+	if calls != 1 {
+		t.Errorf("POST: got %d calls, want 1", calls)
+	}
+
+	// GETs give up after MaxRetries
+	atomic.StoreInt32(&calls, 0)
+	_, err = gh.Git("GET", "/repos/org/repo/issues/1", "")
+	apiErr := &APIError{}
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 503 ||
+		!strings.Contains(apiErr.Message, "unavailable") {
+		t.Errorf("got %v, want the 503", err)
+	}
+	if calls != 4 {
+		t.Errorf("GET: got %d calls, want 4", calls)
+	}
+}
@@ -1,19 +1,62 @@
 package github
 
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
 // https://docs.github.com/en/developers/webhooks-and-events/webhook-events-and-payloads
 
 type GitHubClient struct {
-	Host   string
-	Token  string
-	Secret string // used to verify events are from github
+	Host       string
+	Token      string
+	Secret     string   // used to verify events are from github
+	OldSecrets []string // also accepted by VerifyEvent while rotating Secret
+
+	HTTPClient *http.Client // nil means use a shared default client
+	Retry      *RetryPolicy // nil means use DefaultRetryPolicy
+	Cache      Cache        // nil means no conditional GETs
+	DataStore  DataStore    // nil means BodyStore
+	me         *tokenUser
+	ctx        context.Context
+}
+
+// tokenUser caches the login of the Token's user. It's a pointer so the
+// copies made by WithContext share it.
+type tokenUser struct {
+	mu    sync.Mutex
+	login string
+}
+
+// NewGitHubClient panics if one of the options is bad (e.g. an unreadable CA
+// file), use NewGitHubClientE to get the error instead
+func NewGitHubClient(host string, token string, secret string, opts ...ClientOption) *GitHubClient {
+	client, err := NewGitHubClientE(host, token, secret, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return client
 }
 
-func NewGitHubClient(host string, token string, secret string) *GitHubClient {
+func NewGitHubClientE(host string, token string, secret string, opts ...ClientOption) (*GitHubClient, error) {
+	cfg := newClientConfig(opts)
+	client, err := cfg.NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
 	return &GitHubClient{
 		Host:   host,
 		Token:  token,
 		Secret: secret,
-	}
+
+		HTTPClient: client,
+		OldSecrets: cfg.oldSecrets,
+		Retry:      cfg.retry,
+		Cache:      cfg.cache,
+		DataStore:  cfg.dataStore,
+		me:         &tokenUser{},
+	}, nil
 }
 
 type User struct {
@@ -317,6 +360,51 @@ type Event_Milestone struct {
 
 type Event_Pull_Request struct {
 	*GitHubClient
+
+	Action       string
+	Number       int
+	Pull_Request *PullRequest
+	Changes      struct { // only for "edited" actions
+		Title struct {
+			From string
+		}
+		Body struct {
+			From string
+		}
+		Base struct {
+			Ref struct {
+				From string
+			}
+			SHA struct {
+				From string
+			}
+		}
+	}
+	Before             string // only for "synchronize" actions
+	After              string
+	Requested_Reviewer *User
+	Requested_Team     *Team
+	Assignee           *User
+	Label              *Label
+	Repository         *Repository
+	Organization       *Organization
+	Sender             *User
+}
+
+type Event_Pull_Request_Review struct {
+	*GitHubClient
+
+	Action  string
+	Review  *Review
+	Changes struct { // only for "edited" actions
+		Body struct {
+			From string
+		}
+	}
+	Pull_Request *PullRequest
+	Repository   *Repository
+	Organization *Organization
+	Sender       *User
 }
 
 type Event_Push struct {
@@ -409,3 +497,156 @@ type Card struct {
 	Column_URL  string
 	Content_URL string
 }
+
+// CardPosition is where a Card goes in a Column: CardTop, CardBottom or
+// CardAfter(otherCard)
+type CardPosition string
+
+const (
+	CardTop    CardPosition = "top"
+	CardBottom CardPosition = "bottom"
+)
+
+// ProjectV2 is a (new style) GitHub Project, only reachable via GraphQL
+type ProjectV2 struct {
+	*GitHubClient
+
+	ID     string // node ID
+	Number int
+	Title  string
+	URL    string
+	Closed bool
+	Owner  string // login of the org or user
+
+	Fields []*ProjectV2Field // see GetFields
+}
+
+// ProjectV2Field DataType is TEXT, NUMBER, DATE, SINGLE_SELECT, ITERATION
+// or one of the built-in types (TITLE, ASSIGNEES, ...)
+type ProjectV2Field struct {
+	ID         string
+	Name       string
+	DataType   string
+	Options    []*ProjectV2Option    // SINGLE_SELECT
+	Iterations []*ProjectV2Iteration // ITERATION, active and completed
+}
+
+type ProjectV2Option struct {
+	ID   string
+	Name string
+}
+
+type ProjectV2Iteration struct {
+	ID        string
+	Title     string
+	StartDate string
+	Duration  int // days
+}
+
+// ProjectV2Item is an issue, PR or draft issue in a ProjectV2. Values has
+// the value of each field that's set, by field name. Single select and
+// iteration values are the option name and iteration title.
+type ProjectV2Item struct {
+	*GitHubClient
+
+	Project *ProjectV2
+	ID      string
+	Type    string // ISSUE, PULL_REQUEST or DRAFT_ISSUE
+	Number  int
+	Title   string
+	URL     string // of the issue or PR
+	Values  map[string]string
+}
+
+type PullRequest struct {
+	*GitHubClient
+
+	ID                  int
+	Node_ID             string
+	URL                 string
+	HTML_URL            string
+	Diff_URL            string
+	Patch_URL           string
+	Issue_URL           string
+	Commits_URL         string
+	Review_Comments_URL string
+	Comments_URL        string
+	Statuses_URL        string
+	Number              int
+	State               string
+	Locked              bool
+	Title               string
+	Body                string
+	User                *User
+	Labels              []*Label
+	Milestone           *Milestone
+	Assignee            *User
+	Assignees           []*User
+	Requested_Reviewers []*User
+	Requested_Teams     []*Team
+	Head                *PullRequestRef
+	Base                *PullRequestRef
+	Draft               bool
+	Merged              bool
+	Mergeable           *bool // nil until GitHub has computed it
+	Rebaseable          *bool
+	Mergeable_State     string
+	Merged_By           *User
+	Merge_Commit_SHA    string
+	Author_Association  string
+	Comments            int
+	Review_Comments     int
+	Commits             int
+	Additions           int
+	Deletions           int
+	Changed_Files       int
+	Created_At          string
+	Updated_At          string
+	Closed_At           string
+	Merged_At           string
+}
+
+type PullRequestRef struct {
+	*GitHubClient
+
+	Label string
+	Ref   string
+	SHA   string
+	User  *User
+	Repo  *Repository
+}
+
+type PullRequestFile struct {
+	SHA               string
+	Filename          string
+	Status            string // added, removed, modified, renamed, ...
+	Additions         int
+	Deletions         int
+	Changes           int
+	Blob_URL          string
+	Raw_URL           string
+	Contents_URL      string
+	Patch             string
+	Previous_Filename string
+}
+
+type Review struct {
+	*GitHubClient
+
+	ID                 int
+	Node_ID            string
+	User               *User
+	Body               string
+	State              string // APPROVED, CHANGES_REQUESTED, COMMENTED, ...
+	HTML_URL           string
+	Pull_Request_URL   string
+	Commit_ID          string
+	Submitted_At       string
+	Author_Association string
+}
+
+type MergeResult struct {
+	SHA     string
+	Merged  bool
+	Message string
+}
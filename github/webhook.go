@@ -0,0 +1,189 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+)
+
+// Largest payload GitHub will send is 25MB
+const maxWebhookBody = 25 * 1024 * 1024
+
+// WebhookRouter is an http.Handler for GitHub webhooks. It verifies the
+// signature of each delivery, decodes the payload into the matching
+// Event_* type, calls SetGH() on it and then calls the callbacks
+// registered for that event:
+//
+//	router := github.NewWebhookRouter(gh)
+//	router.OnIssues(func(e *github.Event_Issues) error { ... })
+//	http.Handle("/webhook", router)
+//
+// Callbacks are called synchronously, in the order they were registered,
+// before the response is sent back to GitHub. Deliveries whose
+// X-GitHub-Delivery ID was already processed are acknowledged but skipped.
+// If a callback returns an error the rest are skipped, the error goes to
+// the OnError func and GitHub is told the delivery failed. When it's
+// redelivered only the callbacks that didn't succeed yet are called.
+type WebhookRouter struct {
+	*GitHubClient
+
+	SkipVerify bool           // don't check the signatures, only for testing
+	Deliveries *DeliveryCache // nil turns off de-duplication
+
+	handlers map[string][]func(body []byte) error
+	onError  func(req *http.Request, err error)
+}
+
+func NewWebhookRouter(gh *GitHubClient) *WebhookRouter {
+	return &WebhookRouter{
+		GitHubClient: gh,
+		Deliveries:   NewDeliveryCache(1000),
+		handlers:     map[string][]func(body []byte) error{},
+		onError: func(req *http.Request, err error) {
+			log.Printf("Webhook: %s", err)
+		},
+	}
+}
+
+// OnError sets the func that's called for any error while processing a
+// delivery (bad signature, bad JSON, ...). By default they're just logged.
+func (wr *WebhookRouter) OnError(fn func(req *http.Request, err error)) {
+	wr.onError = fn
+}
+
+// OnEvent registers a callback for the raw payload of any event type,
+// including ones that don't have an Event_* type yet.
+func (wr *WebhookRouter) OnEvent(event string, fn func(body []byte) error) {
+	wr.handlers[event] = append(wr.handlers[event], fn)
+}
+
+func (wr *WebhookRouter) OnIssues(fn func(*Event_Issues) error) {
+	wr.OnEvent("issues", func(body []byte) error {
+		e := Event_Issues{}
+		if err := json.Unmarshal(body, &e); err != nil {
+			return err
+		}
+		e.SetGH(wr.GitHubClient)
+		return fn(&e)
+	})
+}
+
+func (wr *WebhookRouter) OnIssueComment(fn func(*Event_Issue_Comment) error) {
+	wr.OnEvent("issue_comment", func(body []byte) error {
+		e := Event_Issue_Comment{}
+		if err := json.Unmarshal(body, &e); err != nil {
+			return err
+		}
+		e.SetGH(wr.GitHubClient)
+		return fn(&e)
+	})
+}
+
+func (wr *WebhookRouter) OnMilestone(fn func(*Event_Milestone) error) {
+	wr.OnEvent("milestone", func(body []byte) error {
+		e := Event_Milestone{}
+		if err := json.Unmarshal(body, &e); err != nil {
+			return err
+		}
+		e.SetGH(wr.GitHubClient)
+		return fn(&e)
+	})
+}
+
+func (wr *WebhookRouter) OnPush(fn func(*Event_Push) error) {
+	wr.OnEvent("push", func(body []byte) error {
+		e := Event_Push{}
+		if err := json.Unmarshal(body, &e); err != nil {
+			return err
+		}
+		e.SetGH(wr.GitHubClient)
+		return fn(&e)
+	})
+}
+
+func (wr *WebhookRouter) OnPullRequest(fn func(*Event_Pull_Request) error) {
+	wr.OnEvent("pull_request", func(body []byte) error {
+		e := Event_Pull_Request{}
+		if err := json.Unmarshal(body, &e); err != nil {
+			return err
+		}
+		e.SetGH(wr.GitHubClient)
+		return fn(&e)
+	})
+}
+
+func (wr *WebhookRouter) OnPullRequestReview(fn func(*Event_Pull_Request_Review) error) {
+	wr.OnEvent("pull_request_review", func(body []byte) error {
+		e := Event_Pull_Request_Review{}
+		if err := json.Unmarshal(body, &e); err != nil {
+			return err
+		}
+		e.SetGH(wr.GitHubClient)
+		return fn(&e)
+	})
+}
+
+func (wr *WebhookRouter) reportError(req *http.Request, err error) {
+	if wr.onError != nil {
+		wr.onError(req, err)
+	}
+}
+
+func (wr *WebhookRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxWebhookBody))
+	if err != nil {
+		wr.reportError(req, fmt.Errorf("Error reading body: %s", err))
+		http.Error(w, "Error reading body", http.StatusBadRequest)
+		return
+	}
+
+	if !wr.SkipVerify && !wr.VerifyEvent(req, body) {
+		wr.reportError(req, fmt.Errorf("Invalid signature for delivery %q",
+			req.Header.Get("X-GitHub-Delivery")))
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event := req.Header.Get("X-GitHub-Event")
+	if event == "" {
+		wr.reportError(req, fmt.Errorf("Missing X-GitHub-Event header"))
+		http.Error(w, "Missing X-GitHub-Event header", http.StatusBadRequest)
+		return
+	}
+
+	delivery := req.Header.Get("X-GitHub-Delivery")
+	if wr.Deliveries != nil && delivery != "" && wr.Deliveries.Seen(delivery) {
+		// Already processed, GitHub is just redelivering it
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	track := wr.Deliveries != nil && delivery != ""
+	for i, handler := range wr.handlers[event] {
+		if track && wr.Deliveries.IsHandled(delivery, i) {
+			continue // done by an earlier attempt
+		}
+		if err = handler(body); err != nil {
+			if track {
+				wr.Deliveries.Forget(delivery) // allow a retry
+			}
+			wr.reportError(req, fmt.Errorf("Error processing %q event (%s): %s",
+				event, delivery, err))
+			http.Error(w, "Error processing event", http.StatusBadRequest)
+			return
+		}
+		if track {
+			wr.Deliveries.Handled(delivery, i)
+		}
+	}
+
+	// Unknown events (and "ping") are just accepted and ignored
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,179 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func sign(h func() hash.Hash, secret string, body []byte) string {
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyEvent(t *testing.T) {
+	body := []byte(`{"action":"opened"}`)
+	gh := NewGitHubClient("github.com", "token", "new",
+		WithOldSecrets("old", ""))
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		ok     bool
+	}{
+		{"sha256 secret", "X-Hub-Signature-256",
+			"sha256=" + sign(sha256.New, "new", body), true},
+		{"sha256 old secret", "X-Hub-Signature-256",
+			"sha256=" + sign(sha256.New, "old", body), true},
+		{"sha256 unknown secret", "X-Hub-Signature-256",
+			"sha256=" + sign(sha256.New, "other", body), false},
+		{"sha1 secret", "X-Hub-Signature",
+			"sha1=" + sign(sha1.New, "new", body), true},
+		{"sha1 old secret", "X-Hub-Signature",
+			"sha1=" + sign(sha1.New, "old", body), true},
+		{"sha1 sig in sha256 header", "X-Hub-Signature-256",
+			"sha256=" + sign(sha1.New, "new", body), false},
+		{"wrong prefix", "X-Hub-Signature-256",
+			"sha1=" + sign(sha256.New, "new", body), false},
+		{"not hex", "X-Hub-Signature-256",
+			"sha256=" + string(make([]byte, 64)), false},
+		{"empty secret", "X-Hub-Signature-256",
+			"sha256=" + sign(sha256.New, "", body), false},
+		{"no signature", "", "", false},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest("POST", "/", nil)
+		if test.header != "" {
+			req.Header.Set(test.header, test.value)
+		}
+		if ok := gh.VerifyEvent(req, body); ok != test.ok {
+			t.Errorf("%s: got %v, want %v", test.name, ok, test.ok)
+		}
+	}
+
+	// The sha256 header wins when both are there
+	req, _ := http.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Hub-Signature", "sha1="+sign(sha1.New, "new", body))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+sign(sha256.New, "x", body))
+	if gh.VerifyEvent(req, body) {
+		t.Errorf("bad sha256 signature was accepted due to the sha1 one")
+	}
+
+	// Tampered body
+	req, _ = http.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+sign(sha256.New, "old", body))
+	if gh.VerifyEvent(req, []byte(`{"action":"closed"}`)) {
+		t.Errorf("signature of another body was accepted")
+	}
+}
+
+func TestDeliveryCache(t *testing.T) {
+	dc := NewDeliveryCache(2)
+
+	if dc.Seen("a") {
+		t.Errorf("new delivery was seen")
+	}
+	if !dc.Seen("a") {
+		t.Errorf("redelivery wasn't seen")
+	}
+
+	// A failed delivery is let through once
+	dc.Handled("a", 0)
+	dc.Forget("a")
+	if dc.Seen("a") {
+		t.Errorf("forgotten delivery was seen")
+	}
+	if !dc.Seen("a") {
+		t.Errorf("retried delivery wasn't seen")
+	}
+	if !dc.IsHandled("a", 0) || dc.IsHandled("a", 1) {
+		t.Errorf("handlers of the delivery were lost")
+	}
+
+	// Oldest is dropped once full, "a" was used more recently than "b"
+	dc.Seen("b")
+	dc.Seen("a")
+	dc.Seen("c")
+	if dc.Seen("b") {
+		t.Errorf("oldest delivery wasn't dropped")
+	}
+	if !dc.Seen("c") {
+		t.Errorf("newest delivery was dropped")
+	}
+}
+
+func TestWebhookRouter(t *testing.T) {
+	gh := NewGitHubClient("github.com", "token", "secret")
+	wr := NewWebhookRouter(gh)
+	wr.OnError(func(req *http.Request, err error) {})
+
+	calls := []string{}
+	fail := true
+	wr.OnIssues(func(e *Event_Issues) error {
+		calls = append(calls, "first:"+e.Action)
+		return nil
+	})
+	wr.OnIssues(func(e *Event_Issues) error {
+		calls = append(calls, "second:"+e.Action)
+		if fail {
+			return errors.New("oops")
+		}
+		return nil
+	})
+
+	send := func(event, delivery, sig string) int {
+		body := `{"action":"opened","issue":{"number":1}}`
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-GitHub-Delivery", delivery)
+		if sig == "" {
+			sig = "sha256=" + sign(sha256.New, "secret", []byte(body))
+		}
+		req.Header.Set("X-Hub-Signature-256", sig)
+		w := httptest.NewRecorder()
+		wr.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send("issues", "d1", "sha256=00"); code != 401 {
+		t.Errorf("bad signature: got %d, want 401", code)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("handlers called for a bad signature: %v", calls)
+	}
+
+	if code := send("issues", "d1", ""); code != 400 {
+		t.Errorf("failed handler: got %d, want 400", code)
+	}
+
+	// The redelivery only runs the handler that failed
+	fail = false
+	if code := send("issues", "d1", ""); code != 200 {
+		t.Errorf("redelivery: got %d, want 200", code)
+	}
+
+	// Once it's done more redeliveries are skipped
+	if code := send("issues", "d1", ""); code != 200 {
+		t.Errorf("duplicate: got %d, want 200", code)
+	}
+
+	// Unknown events are accepted
+	if code := send("watch", "d2", ""); code != 200 {
+		t.Errorf("unknown event: got %d, want 200", code)
+	}
+
+	want := "first:opened second:opened second:opened"
+	if got := strings.Join(calls, " "); got != want {
+		t.Errorf("got calls %q, want %q", got, want)
+	}
+}
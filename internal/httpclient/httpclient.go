@@ -0,0 +1,105 @@
+// Package httpclient builds the http.Client used by the github, aha and
+// zenhub clients from their ClientOptions.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Default is shared by all clients that don't have their own HTTPClient so
+// that connections are reused across calls
+var Default = &http.Client{
+	Transport: http.DefaultTransport.(*http.Transport).Clone(),
+}
+
+// Config is embedded in each package's clientConfig, the With* options
+// there just set these fields
+type Config struct {
+	HTTPClient *http.Client
+	Transport  http.RoundTripper
+	RootCAs    *x509.CertPool
+	Proxy      string
+	Timeout    time.Duration
+	Insecure   bool
+	Err        error // the first bad option
+}
+
+func (cfg *Config) setErr(err error) {
+	if cfg.Err == nil {
+		cfg.Err = err
+	}
+}
+
+// AddCABundle adds the PEM encoded certificates to the system ones
+func (cfg *Config) AddCABundle(pem []byte) {
+	if cfg.RootCAs == nil {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		cfg.RootCAs = pool
+	}
+	if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+		cfg.setErr(fmt.Errorf("No valid certificates found in CA bundle"))
+	}
+}
+
+func (cfg *Config) AddCAFile(file string) {
+	pem, err := ioutil.ReadFile(file)
+	if err != nil {
+		cfg.setErr(fmt.Errorf("Error reading CA file %q: %s", file, err))
+		return
+	}
+	cfg.AddCABundle(pem)
+}
+
+func (cfg *Config) SetProxy(proxy string) {
+	if _, err := url.Parse(proxy); err != nil {
+		cfg.setErr(fmt.Errorf("Error parsing proxy URL %q: %s", proxy, err))
+		return
+	}
+	cfg.Proxy = proxy
+}
+
+// NewHTTPClient returns nil if none of the HTTP options were used, which
+// means use Default
+func (cfg *Config) NewHTTPClient() (*http.Client, error) {
+	if cfg.Err != nil {
+		return nil, cfg.Err
+	}
+	if cfg.HTTPClient != nil {
+		return cfg.HTTPClient, nil
+	}
+	if cfg.Transport == nil && cfg.RootCAs == nil && cfg.Proxy == "" &&
+		cfg.Timeout == 0 && !cfg.Insecure {
+		return nil, nil
+	}
+
+	rt := cfg.Transport
+	if rt == nil {
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		if cfg.RootCAs != nil || cfg.Insecure {
+			tr.TLSClientConfig = &tls.Config{
+				RootCAs:            cfg.RootCAs,
+				InsecureSkipVerify: cfg.Insecure,
+			}
+		}
+		if cfg.Proxy != "" {
+			proxyURL, err := url.Parse(cfg.Proxy)
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing proxy URL %q: %s",
+					cfg.Proxy, err)
+			}
+			tr.Proxy = http.ProxyURL(proxyURL)
+		}
+		rt = tr
+	}
+
+	return &http.Client{Transport: rt, Timeout: cfg.Timeout}, nil
+}
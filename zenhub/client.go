@@ -0,0 +1,89 @@
+package zenhub
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/duglin/integration/internal/httpclient"
+)
+
+type clientConfig struct {
+	httpclient.Config
+}
+
+// ClientOption configures how a ZenHubClient talks to the server.
+// See NewZenHubClientE.
+type ClientOption func(*clientConfig)
+
+// WithHTTPClient uses the supplied http.Client as is. All other HTTP
+// options are ignored when this one is present.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(cfg *clientConfig) { cfg.HTTPClient = client }
+}
+
+// WithTransport uses the supplied RoundTripper instead of building one.
+// The CA, proxy and TLS options are ignored when this one is present.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(cfg *clientConfig) { cfg.Transport = rt }
+}
+
+// WithCABundle adds the PEM encoded certificates to the set of trusted
+// root CAs (in addition to the system ones).
+func WithCABundle(pem []byte) ClientOption {
+	return func(cfg *clientConfig) { cfg.AddCABundle(pem) }
+}
+
+// WithCAFile is the same as WithCABundle but reads the PEM from a file.
+func WithCAFile(file string) ClientOption {
+	return func(cfg *clientConfig) { cfg.AddCAFile(file) }
+}
+
+// WithProxy sends all requests through the proxy at proxyURL. By default
+// the standard HTTPS_PROXY/NO_PROXY env vars are used.
+func WithProxy(proxyURL string) ClientOption {
+	return func(cfg *clientConfig) { cfg.SetProxy(proxyURL) }
+}
+
+// WithTimeout sets the overall timeout for each HTTP request.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(cfg *clientConfig) { cfg.Timeout = timeout }
+}
+
+// WithInsecureSkipVerify turns off TLS certificate verification. Only
+// use this for testing against servers with self-signed certs.
+func WithInsecureSkipVerify() ClientOption {
+	return func(cfg *clientConfig) { cfg.Insecure = true }
+}
+
+func newClientConfig(opts []ClientOption) *clientConfig {
+	cfg := &clientConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithContext returns a copy of the client that uses ctx for every
+// request. Anything retrieved through the copy inherits ctx too:
+//
+//	board, err := zc.WithContext(ctx).GetBoard(repoID, "Planning")
+func (zc *ZenHubClient) WithContext(ctx context.Context) *ZenHubClient {
+	newClient := *zc
+	newClient.ctx = ctx
+	return &newClient
+}
+
+func (zc *ZenHubClient) context() context.Context {
+	if zc.ctx != nil {
+		return zc.ctx
+	}
+	return context.Background()
+}
+
+func (zc *ZenHubClient) httpClient() *http.Client {
+	if zc.HTTPClient != nil {
+		return zc.HTTPClient
+	}
+	return httpclient.Default
+}
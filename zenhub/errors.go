@@ -0,0 +1,69 @@
+package zenhub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Use these with errors.Is() to check what kind of error Zen() returned:
+//
+//	if _, err := zc.Zen("GET", url, ""); errors.Is(err, zenhub.ErrNotFound) {
+var (
+	ErrUnauthorized = errors.New("zenhub: unauthorized")
+	ErrForbidden    = errors.New("zenhub: forbidden")
+	ErrNotFound     = errors.New("zenhub: not found")
+	ErrRateLimited  = errors.New("zenhub: rate limited")
+	ErrServer       = errors.New("zenhub: server error")
+)
+
+// APIError is returned by Zen() for any non-2xx response
+type APIError struct {
+	StatusCode int
+	Message    string
+
+	Method  string
+	URL     string
+	ReqBody string
+	Body    []byte // raw response body
+}
+
+func newAPIError(method, url, reqBody string, statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Method:     method,
+		URL:        url,
+		ReqBody:    reqBody,
+		Body:       body,
+	}
+
+	// {"message":"Not Found"}
+	zenErr := struct {
+		Message string
+	}{}
+	if err := json.Unmarshal(body, &zenErr); err == nil {
+		apiErr.Message = zenErr.Message
+	}
+
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Error zening: %d %s\n", e.StatusCode, string(e.Body))
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == 401
+	case ErrForbidden:
+		return e.StatusCode == 403
+	case ErrNotFound:
+		return e.StatusCode == 404
+	case ErrRateLimited:
+		return e.StatusCode == 429
+	case ErrServer:
+		return e.StatusCode/100 == 5
+	}
+	return false
+}
@@ -2,7 +2,7 @@ package zenhub
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -12,6 +12,10 @@ import (
 // https://github.com/ZenHubIO/API
 
 func (zc *ZenHubClient) Zen(method string, url string, body string) (string, error) {
+	return zc.ZenContext(zc.context(), method, url, body)
+}
+
+func (zc *ZenHubClient) ZenContext(ctx context.Context, method string, url string, body string) (string, error) {
 	if zc.Token == "" {
 		return "", fmt.Errorf("Missing ZubHun Token, perhaps .zenToken is missing?")
 	}
@@ -20,18 +24,20 @@ func (zc *ZenHubClient) Zen(method string, url string, body string) (string, err
 	if body != "" {
 		buf = []byte(body)
 	}
-	req, err := http.NewRequest(method, url, bytes.NewReader(buf))
+	req, err := http.NewRequestWithContext(ctx, method, url,
+		bytes.NewReader(buf))
+	if err != nil {
+		return "", err
+	}
 
 	req.Header.Add("X-Authentication-Token", zc.Token)
 	req.Header.Add("Content-Type", "application/json")
 
 	// fmt.Printf("*** ZEN: %s %s\n%s\n", method, url, body)
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
+	client := zc.httpClient()
 
-	res, err := (&http.Client{Transport: tr}).Do(req)
+	res, err := client.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -41,7 +47,7 @@ func (zc *ZenHubClient) Zen(method string, url string, body string) (string, err
 	if res.StatusCode/100 != 2 {
 		fmt.Printf("Zen Error:\n--> %s %s\n--> %s\n", method, url, body)
 		fmt.Printf("%d %s\n", res.StatusCode, string(buf))
-		return "", fmt.Errorf("Error zening: %d %s\n", res.StatusCode, string(buf))
+		return "", newAPIError(method, url, body, res.StatusCode, buf)
 	}
 	// fmt.Printf("URL %s\nHeaders:%s\nBody:%s\n\n", url, res.Header, string(buf))
 	return string(buf), nil
@@ -74,17 +80,40 @@ func (zc *ZenHubClient) MakeEpic(repoID int, issueNum int) error {
 
 // POST /p2/workspaces/:workspace_id/repositories/:repo_id/issues/:issue_number/moves
 func (zc *ZenHubClient) SetIssuePipeline(workspaceID string, repoID int, issueNum int, pipelineID string) error {
+	return zc.MoveIssue(workspaceID, repoID, issueNum, pipelineID, Top)
+}
+
+// MoveIssue moves the issue to the pipeline at 'pos'. Positions relative
+// to another issue (AfterIssue/BeforeIssue) need the Board, see
+// Board.MoveIssue.
+func (zc *ZenHubClient) MoveIssue(workspaceID string, repoID int, issueNum int, pipelineID string, pos Position) error {
+	position, err := pos.value()
+	if err != nil {
+		return err
+	}
+
 	url := fmt.Sprintf("%s/p2/workspaces/%s/repositories/%d/issues/%d/moves",
 		zc.URL, workspaceID, repoID, issueNum)
-	body := fmt.Sprintf(`{"pipeline_id":"%s","position":"top"}`, pipelineID)
+	body := fmt.Sprintf(`{"pipeline_id":%q,"position":%s}`, pipelineID,
+		position)
 
-	res, err := zc.Zen("POST", url, string(body))
+	res, err := zc.Zen("POST", url, body)
 	if err != nil {
-		err = fmt.Errorf("Error setting pipeline: %s\n%s", err, res)
+		err = fmt.Errorf("Error setting pipeline: %w\n%s", err, res)
 	}
 	return err
 }
 
+// MoveIssues gets the board once and then does all of the moves, in
+// order. See Board.MoveIssues.
+func (zc *ZenHubClient) MoveIssues(repoID int, workspace string, moves []*Move) ([]*MoveResult, error) {
+	board, err := zc.GetBoard(repoID, workspace)
+	if err != nil {
+		return nil, err
+	}
+	return board.MoveIssues(moves), nil
+}
+
 func (zc *ZenHubClient) SetIssuePipeline2(repoID int, workspace string, issueNum int, pipeline string) error {
 	board, err := zc.GetBoard(repoID, workspace)
 	if err != nil {
@@ -212,3 +241,263 @@ func (zc *ZenHubClient) AddTask(epicRepoID int, epicNum int, taskRepoID int, tas
 	_, err := zc.Zen("POST", url, body)
 	return err
 }
+
+func (zc *ZenHubClient) RemoveTask(epicRepoID int, epicNum int, taskRepoID int, taskNum int) error {
+	url := fmt.Sprintf("%s/p1/repositories/%d/epics/%d/update_issues",
+		zc.URL, epicRepoID, epicNum)
+	body := fmt.Sprintf(`{"remove_issues":[{"repo_id":%d,"issue_number":%d}]}`,
+		taskRepoID, taskNum)
+	_, err := zc.Zen("POST", url, body)
+	return err
+}
+
+func (zc *ZenHubClient) ConvertToIssue(repoID int, epicNum int) error {
+	url := fmt.Sprintf("%s/p1/repositories/%d/epics/%d/convert_to_issue",
+		zc.URL, repoID, epicNum)
+	_, err := zc.Zen("POST", url, "")
+	return err
+}
+
+func (zc *ZenHubClient) GetEpics(repoID int) (*RepositoryEpics, error) {
+	url := fmt.Sprintf("%s/p1/repositories/%d/epics", zc.URL, repoID)
+	res, err := zc.Zen("GET", url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	epics := RepositoryEpics{}
+	if err = json.Unmarshal([]byte(res), &epics); err != nil {
+		return nil, err
+	}
+	epics.ZenHubClient = zc
+
+	return &epics, nil
+}
+
+func (zc *ZenHubClient) GetEpic(repoID int, epicNum int) (*Epic, error) {
+	url := fmt.Sprintf("%s/p1/repositories/%d/epics/%d", zc.URL, repoID,
+		epicNum)
+	res, err := zc.Zen("GET", url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	epic := Epic{}
+	if err = json.Unmarshal([]byte(res), &epic); err != nil {
+		return nil, err
+	}
+	epic.ZenHubClient = zc
+	epic.RepoID = repoID
+	epic.Number = epicNum
+
+	return &epic, nil
+}
+
+// Progress counts the epic's issues (and estimates) that are in the
+// "Closed" pipeline
+func (epic *Epic) Progress() EpicProgress {
+	progress := EpicProgress{}
+	for _, issue := range epic.Issues {
+		estimate := 0
+		if issue.Estimate != nil {
+			estimate = issue.Estimate.Value
+		}
+
+		closed := issue.Pipeline != nil && issue.Pipeline.Name == "Closed"
+		for _, p := range issue.Pipelines {
+			if p.Name == "Closed" {
+				closed = true
+			}
+		}
+
+		progress.Issues++
+		progress.Estimate += estimate
+		if closed {
+			progress.ClosedIssues++
+			progress.ClosedEstimate += estimate
+		}
+	}
+	return progress
+}
+
+func (epic *Epic) AddTask(taskRepoID int, taskNum int) error {
+	return epic.ZenHubClient.AddTask(epic.RepoID, epic.Number, taskRepoID, taskNum)
+}
+
+func (epic *Epic) RemoveTask(taskRepoID int, taskNum int) error {
+	return epic.ZenHubClient.RemoveTask(epic.RepoID, epic.Number, taskRepoID, taskNum)
+}
+
+// PUT /p1/repositories/:repo_id/issues/:issue_number/estimate
+func (zc *ZenHubClient) SetEstimate(repoID int, issueNum int, estimate int) error {
+	url := fmt.Sprintf("%s/p1/repositories/%d/issues/%d/estimate",
+		zc.URL, repoID, issueNum)
+	body := fmt.Sprintf(`{"estimate":%d}`, estimate)
+	_, err := zc.Zen("PUT", url, body)
+	return err
+}
+
+func (zc *ZenHubClient) GetReleaseReports(repoID int) ([]*ReleaseReport, error) {
+	url := fmt.Sprintf("%s/p1/repositories/%d/reports/releases", zc.URL,
+		repoID)
+	res, err := zc.Zen("GET", url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []*ReleaseReport{}
+	if err = json.Unmarshal([]byte(res), &reports); err != nil {
+		return nil, err
+	}
+	for _, r := range reports {
+		r.ZenHubClient = zc
+	}
+	return reports, nil
+}
+
+func (zc *ZenHubClient) GetReleaseReport(releaseID string) (*ReleaseReport, error) {
+	url := fmt.Sprintf("%s/p1/reports/release/%s", zc.URL, releaseID)
+	res, err := zc.Zen("GET", url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	report := ReleaseReport{}
+	if err = json.Unmarshal([]byte(res), &report); err != nil {
+		return nil, err
+	}
+	report.ZenHubClient = zc
+
+	return &report, nil
+}
+
+func (zc *ZenHubClient) GetReleaseReportByTitle(repoID int, title string) (*ReleaseReport, error) {
+	reports, err := zc.GetReleaseReports(repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range reports {
+		if r.Title == title {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (report *ReleaseReport) GetIssues() ([]IssueRef, error) {
+	url := fmt.Sprintf("%s/p1/reports/release/%s/issues",
+		report.ZenHubClient.URL, report.Release_ID)
+	res, err := report.Zen("GET", url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []IssueRef{}
+	if err = json.Unmarshal([]byte(res), &issues); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+func (zc *ZenHubClient) AddIssueToRelease(releaseID string, repoID int, issueNum int) error {
+	url := fmt.Sprintf("%s/p1/reports/release/%s/issues", zc.URL, releaseID)
+	body := fmt.Sprintf(`{"add_issues":[{"repo_id":%d,"issue_number":%d}],"remove_issues":[]}`,
+		repoID, issueNum)
+	_, err := zc.Zen("PATCH", url, body)
+	return err
+}
+
+func (zc *ZenHubClient) RemoveIssueFromRelease(releaseID string, repoID int, issueNum int) error {
+	url := fmt.Sprintf("%s/p1/reports/release/%s/issues", zc.URL, releaseID)
+	body := fmt.Sprintf(`{"add_issues":[],"remove_issues":[{"repo_id":%d,"issue_number":%d}]}`,
+		repoID, issueNum)
+	_, err := zc.Zen("PATCH", url, body)
+	return err
+}
+
+func (zc *ZenHubClient) GetDependencies(repoID int) ([]*Dependency, error) {
+	url := fmt.Sprintf("%s/p1/repositories/%d/dependencies", zc.URL, repoID)
+	res, err := zc.Zen("GET", url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	deps := struct {
+		Dependencies []*Dependency `json:"dependencies"`
+	}{}
+	if err = json.Unmarshal([]byte(res), &deps); err != nil {
+		return nil, err
+	}
+	return deps.Dependencies, nil
+}
+
+// AddDependency marks blocked (repo/num) as being blocked by blocking
+func (zc *ZenHubClient) AddDependency(blocking IssueRef, blocked IssueRef) error {
+	return zc.dependency("POST", blocking, blocked)
+}
+
+func (zc *ZenHubClient) RemoveDependency(blocking IssueRef, blocked IssueRef) error {
+	return zc.dependency("DELETE", blocking, blocked)
+}
+
+func (zc *ZenHubClient) dependency(method string, blocking IssueRef, blocked IssueRef) error {
+	buf, err := json.Marshal(Dependency{Blocking: blocking, Blocked: blocked})
+	if err != nil {
+		return err
+	}
+	_, err = zc.Zen(method, zc.URL+"/p1/dependencies", string(buf))
+	return err
+}
+
+// GET /p1/repositories/:repo_id/issues/:issue_number/events
+func (zc *ZenHubClient) GetIssueEvents(repoID int, issueNum int) ([]*IssueEvent, error) {
+	url := fmt.Sprintf("%s/p1/repositories/%d/issues/%d/events", zc.URL,
+		repoID, issueNum)
+	res, err := zc.Zen("GET", url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	events := []*IssueEvent{}
+	if err = json.Unmarshal([]byte(res), &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetIssueTimeline returns the pipelines the issue went through in the
+// workspace, "" means any workspace
+func (zc *ZenHubClient) GetIssueTimeline(repoID int, issueNum int, workspaceID string) (*IssueTimeline, error) {
+	events, err := zc.GetIssueEvents(repoID, issueNum)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IssueTimeline{
+		RepoID: repoID,
+		Number: issueNum,
+		Stints: BuildStints(events, workspaceID),
+	}, nil
+}
+
+// GetTimelines returns the timeline of every issue on the board, by number
+func (board *Board) GetTimelines() (map[int]*IssueTimeline, error) {
+	workspaceID := ""
+	if board.Workspace != nil {
+		workspaceID = board.Workspace.ID
+	}
+
+	timelines := map[int]*IssueTimeline{}
+	for _, p := range board.Pipelines {
+		for _, issue := range p.Issues {
+			tl, err := board.GetIssueTimeline(board.RepoID, issue.Issue_Number,
+				workspaceID)
+			if err != nil {
+				return nil, err
+			}
+			timelines[issue.Issue_Number] = tl
+		}
+	}
+	return timelines, nil
+}
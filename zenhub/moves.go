@@ -0,0 +1,150 @@
+package zenhub
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// AtIndex is a 0 based position in the pipeline
+func AtIndex(index int) Position {
+	return Position{where: "index", index: index}
+}
+
+// AfterIssue places the issue right below issue 'num' of the same repo
+func AfterIssue(num int) Position {
+	return Position{where: "after", issue: num}
+}
+
+// BeforeIssue places the issue right above issue 'num' of the same repo
+func BeforeIssue(num int) Position {
+	return Position{where: "before", issue: num}
+}
+
+// value returns the JSON for the "position" field of a move
+func (pos Position) value() (string, error) {
+	switch pos.where {
+	case "", "top":
+		return `"top"`, nil
+	case "bottom":
+		return `"bottom"`, nil
+	case "index":
+		if pos.index < 0 {
+			return "", fmt.Errorf("Invalid position: %d", pos.index)
+		}
+		return strconv.Itoa(pos.index), nil
+	}
+	return "", fmt.Errorf("Position relative to issue #%d needs a Board",
+		pos.issue)
+}
+
+func (board *Board) GetPipelineByName(name string) *Pipeline {
+	for _, p := range board.Pipelines {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// findIssue returns the pipeline and index of the issue, or nil
+func (board *Board) findIssue(issueNum int) (*Pipeline, int) {
+	for _, p := range board.Pipelines {
+		for i, issue := range p.Issues {
+			if issue.Issue_Number == issueNum {
+				return p, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// MoveIssue moves the issue to the named pipeline at 'pos'. The Board is
+// updated to match so that it can be used for more moves w/o refetching.
+func (board *Board) MoveIssue(issueNum int, pipeline string, pos Position) error {
+	if board.Workspace == nil {
+		return fmt.Errorf("Board has no workspace, use ZenHubClient.GetBoard")
+	}
+
+	dest := board.GetPipelineByName(pipeline)
+	if dest == nil {
+		return fmt.Errorf("Can't find pipeline %q", pipeline)
+	}
+
+	// Take it out of its current pipeline, if it's on the board
+	var moved *PipelineIssue
+	if src, i := board.findIssue(issueNum); src != nil {
+		moved = src.Issues[i]
+	}
+	issues := []*PipelineIssue{}
+	for _, issue := range dest.Issues {
+		if issue.Issue_Number != issueNum {
+			issues = append(issues, issue)
+		}
+	}
+
+	// Turn relative positions into an index
+	index := -1
+	switch pos.where {
+	case "after", "before":
+		for i, issue := range issues {
+			if issue.Issue_Number == pos.issue {
+				index = i
+				if pos.where == "after" {
+					index++
+				}
+				break
+			}
+		}
+		if index == -1 {
+			return fmt.Errorf("Can't find issue #%d in pipeline %q",
+				pos.issue, pipeline)
+		}
+		pos = AtIndex(index)
+	case "", "top":
+		index = 0
+	case "bottom":
+		index = len(issues)
+	case "index":
+		index = pos.index
+	}
+
+	err := board.ZenHubClient.MoveIssue(board.Workspace.ID, board.RepoID,
+		issueNum, dest.ID, pos)
+	if err != nil {
+		return err
+	}
+
+	// Update our copy of the board
+	if src, i := board.findIssue(issueNum); src != nil {
+		src.Issues = append(src.Issues[:i], src.Issues[i+1:]...)
+	}
+	if moved == nil {
+		moved = &PipelineIssue{
+			ZenHubClient: board.ZenHubClient,
+			Issue_Number: issueNum,
+		}
+	}
+	if index > len(dest.Issues) {
+		index = len(dest.Issues)
+	}
+	dest.Issues = append(dest.Issues[:index],
+		append([]*PipelineIssue{moved}, dest.Issues[index:]...)...)
+	for i, issue := range dest.Issues {
+		issue.Position = i
+	}
+
+	return nil
+}
+
+// MoveIssues does the moves in order, one result per move. A failed move
+// doesn't stop the rest.
+func (board *Board) MoveIssues(moves []*Move) []*MoveResult {
+	results := []*MoveResult{}
+	for _, move := range moves {
+		results = append(results, &MoveResult{
+			Issue: move.Issue,
+			Err:   board.MoveIssue(move.Issue, move.Pipeline, move.Position),
+		})
+	}
+	return results
+}
@@ -0,0 +1,111 @@
+package zenhub
+
+import (
+	"sort"
+	"time"
+)
+
+func (e *IssueEvent) CreatedAt() time.Time {
+	t, _ := time.Parse(time.RFC3339, e.Created_At)
+	return t
+}
+
+// BuildStints turns the transfer events of an issue into the list of
+// pipelines it was in. Events from other workspaces are skipped unless
+// workspaceID is "".
+func BuildStints(events []*IssueEvent, workspaceID string) []*PipelineStint {
+	transfers := []*IssueEvent{}
+	for _, e := range events {
+		if e.Type != EventTransfer || e.To_Pipeline == nil {
+			continue
+		}
+		if workspaceID != "" && e.Workspace_ID != "" && e.Workspace_ID != workspaceID {
+			continue
+		}
+		transfers = append(transfers, e)
+	}
+
+	// ZenHub returns the newest first
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return transfers[i].CreatedAt().Before(transfers[j].CreatedAt())
+	})
+
+	stints := []*PipelineStint{}
+	for _, e := range transfers {
+		at := e.CreatedAt()
+		if len(stints) > 0 {
+			stints[len(stints)-1].Exited = at
+		} else if e.From_Pipeline != nil {
+			stints = append(stints, &PipelineStint{
+				Pipeline: e.From_Pipeline.Name,
+				Exited:   at,
+			})
+		}
+		stints = append(stints, &PipelineStint{
+			Pipeline: e.To_Pipeline.Name,
+			Entered:  at,
+		})
+	}
+
+	return stints
+}
+
+// Duration of the stint, up to 'now' if the issue is still in it. Zero if
+// we don't know when it entered.
+func (s *PipelineStint) Duration(now time.Time) time.Duration {
+	if s.Entered.IsZero() {
+		return 0
+	}
+	if s.Exited.IsZero() {
+		return now.Sub(s.Entered)
+	}
+	return s.Exited.Sub(s.Entered)
+}
+
+// Current returns the pipeline the issue is in now, "" if unknown
+func (tl *IssueTimeline) Current() string {
+	if len(tl.Stints) == 0 {
+		return ""
+	}
+	return tl.Stints[len(tl.Stints)-1].Pipeline
+}
+
+// TimeInPipelines adds up the time spent in each pipeline
+func (tl *IssueTimeline) TimeInPipelines(now time.Time) map[string]time.Duration {
+	res := map[string]time.Duration{}
+	for _, s := range tl.Stints {
+		res[s.Pipeline] += s.Duration(now)
+	}
+	return res
+}
+
+// Entered returns when the issue first entered the pipeline
+func (tl *IssueTimeline) Entered(pipeline string) (time.Time, bool) {
+	for _, s := range tl.Stints {
+		if s.Pipeline == pipeline && !s.Entered.IsZero() {
+			return s.Entered, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// CycleTime is the time from first entering 'start' until the last time
+// it entered 'end' (e.g. "In Progress" -> "Closed"). false means the issue
+// hasn't made it through both yet.
+func (tl *IssueTimeline) CycleTime(start string, end string) (time.Duration, bool) {
+	from, ok := tl.Entered(start)
+	if !ok {
+		return 0, false
+	}
+
+	to := time.Time{}
+	for _, s := range tl.Stints {
+		if s.Pipeline == end && s.Entered.After(from) {
+			to = s.Entered
+		}
+	}
+	if to.IsZero() {
+		return 0, false
+	}
+	return to.Sub(from), true
+}
@@ -1,17 +1,42 @@
 package zenhub
 
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
 type ZenHubClient struct {
 	URL    string
 	Token  string
 	Secret string
+
+	HTTPClient *http.Client // nil means use a shared default client
+	ctx        context.Context
+}
+
+// NewZenHubClient panics if one of the options is bad (e.g. an unreadable CA
+// file), use NewZenHubClientE to get the error instead
+func NewZenHubClient(url string, token string, secret string, opts ...ClientOption) *ZenHubClient {
+	client, err := NewZenHubClientE(url, token, secret, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return client
 }
 
-func NewZenHubClient(url string, token string, secret string) *ZenHubClient {
+func NewZenHubClientE(url string, token string, secret string, opts ...ClientOption) (*ZenHubClient, error) {
+	client, err := newClientConfig(opts).NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
 	return &ZenHubClient{
 		URL:    url,
 		Token:  token,
 		Secret: secret,
-	}
+
+		HTTPClient: client,
+	}, nil
 }
 
 // GET /p1/repositories/:repo_id/issues/:issue_number -> Issue
@@ -81,7 +106,128 @@ type RepositoryEpics struct {
 	} `json:"epic_issues"`
 }
 
+type IssuePipeline struct {
+	Name         string `json:"name"`
+	Pipeline_ID  string `json:"pipeline_id"`
+	Workspace_ID string `json:"workspace_id"`
+}
+
+type Estimate struct {
+	Value int `json:"value"`
+}
+
+type EpicIssue struct {
+	Issue_Number int             `json:"issue_number"`
+	Repo_ID      int             `json:"repo_id"`
+	Is_Epic      bool            `json:"is_epic"`
+	Estimate     *Estimate       `json:"estimate"`
+	Pipeline     *IssuePipeline  `json:"pipeline"`
+	Pipelines    []IssuePipeline `json:"pipelines"`
+}
+
 // GET /p1/repositories/:repo_id/epics/:epic_id  -> Epic
 type Epic struct {
 	*ZenHubClient
+
+	RepoID int
+	Number int
+
+	Total_Epic_Estimates Estimate        `json:"total_epic_estimates"`
+	Estimate             *Estimate       `json:"estimate"`
+	Pipeline             *IssuePipeline  `json:"pipeline"`
+	Pipelines            []IssuePipeline `json:"pipelines"`
+	Issues               []*EpicIssue    `json:"issues"`
+}
+
+type EpicProgress struct {
+	Issues         int
+	ClosedIssues   int
+	Estimate       int
+	ClosedEstimate int
+}
+
+type IssueRef struct {
+	Repo_ID      int `json:"repo_id"`
+	Issue_Number int `json:"issue_number"`
+}
+
+// GET /p1/repositories/:repo_id/reports/releases -> []ReleaseReport
+type ReleaseReport struct {
+	*ZenHubClient
+
+	Release_ID       string `json:"release_id"`
+	Title            string `json:"title"`
+	Description      string `json:"description"`
+	Start_Date       string `json:"start_date"`
+	Desired_End_Date string `json:"desired_end_date"`
+	Created_At       string `json:"created_at"`
+	Closed_At        string `json:"closed_at"`
+	State            string `json:"state"`
+	Repositories     []int  `json:"repositories"`
+}
+
+// GET /p1/repositories/:repo_id/dependencies -> Dependencies
+type Dependency struct {
+	Blocking IssueRef `json:"blocking"`
+	Blocked  IssueRef `json:"blocked"`
+}
+
+const (
+	EventTransfer = "transferIssue"
+	EventEstimate = "estimateIssue"
+)
+
+// GET /p1/repositories/:repo_id/issues/:issue_number/events -> []IssueEvent
+type IssueEvent struct {
+	User_ID       int       `json:"user_id"`
+	Type          string    `json:"type"` // EventTransfer or EventEstimate
+	Created_At    string    `json:"created_at"`
+	Workspace_ID  string    `json:"workspace_id"`
+	From_Estimate *Estimate `json:"from_estimate"`
+	To_Estimate   *Estimate `json:"to_estimate"`
+	From_Pipeline *struct {
+		Name string `json:"name"`
+	} `json:"from_pipeline"`
+	To_Pipeline *struct {
+		Name string `json:"name"`
+	} `json:"to_pipeline"`
+}
+
+// PipelineStint is one stay of an issue in a pipeline. Entered is zero if
+// the issue was there before its first recorded move, Exited is zero if
+// it's still there.
+type PipelineStint struct {
+	Pipeline string
+	Entered  time.Time
+	Exited   time.Time
+}
+
+type IssueTimeline struct {
+	RepoID int
+	Number int
+	Stints []*PipelineStint // oldest first
+}
+
+// Position is where in a pipeline an issue is moved to. Use Top, Bottom,
+// AtIndex, AfterIssue or BeforeIssue.
+type Position struct {
+	where string // top, bottom, index, after, before
+	index int
+	issue int
+}
+
+var (
+	Top    = Position{where: "top"}
+	Bottom = Position{where: "bottom"}
+)
+
+type Move struct {
+	Issue    int
+	Pipeline string // name
+	Position Position
+}
+
+type MoveResult struct {
+	Issue int
+	Err   error
 }
@@ -0,0 +1,407 @@
+package zenreport
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/duglin/integration/zenhub"
+)
+
+const timeFormat = "20060102T150405.000000000Z"
+
+// TakeSnapshot records the current state of the board
+func TakeSnapshot(board *zenhub.Board) *Snapshot {
+	snap := &Snapshot{
+		Taken:  time.Now().UTC(),
+		RepoID: board.RepoID,
+	}
+	if board.Workspace != nil {
+		snap.Workspace = board.Workspace.Name
+	}
+
+	for _, p := range board.Pipelines {
+		ps := &PipelineSnapshot{
+			Name:   p.Name,
+			Issues: []int{},
+		}
+		for _, issue := range p.Issues {
+			ps.Issues = append(ps.Issues, issue.Issue_Number)
+			ps.Estimate += issue.Estimate.Value
+		}
+		snap.Pipelines = append(snap.Pipelines, ps)
+	}
+
+	return snap
+}
+
+func (snap *Snapshot) Pipeline(name string) *PipelineSnapshot {
+	for _, p := range snap.Pipelines {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+func (fs *FileStore) Save(snap *Snapshot) error {
+	buf, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// snapshot-REPO-WORKSPACE-TIME[-N].json, O_EXCL so two snapshots
+	// taken at the same time can't overwrite each other
+	prefix := fmt.Sprintf("snapshot-%d-%s-%s", snap.RepoID,
+		fileSafe(snap.Workspace), snap.Taken.UTC().Format(timeFormat))
+	for i := 0; ; i++ {
+		name := prefix + ".json"
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d.json", prefix, i)
+		}
+		file, err := os.OpenFile(filepath.Join(fs.Dir, name),
+			os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err = file.Write(buf); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}
+}
+
+// fileSafe replaces anything but letters, digits, '.' and '_' with '_'
+func fileSafe(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// Load returns the snapshots of the repo's board in the workspace, oldest
+// first. Zero 'from' or 'to' times mean no limit.
+func (fs *FileStore) Load(repoID int, workspace string, from time.Time, to time.Time) ([]*Snapshot, error) {
+	files, err := filepath.Glob(filepath.Join(fs.Dir,
+		fmt.Sprintf("snapshot-%d-*.json", repoID)))
+	if err != nil {
+		return nil, err
+	}
+
+	snaps := []*Snapshot{}
+	for _, file := range files {
+		buf, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		snap := &Snapshot{}
+		if err = json.Unmarshal(buf, snap); err != nil {
+			return nil, fmt.Errorf("Error parsing %q: %s", file, err)
+		}
+
+		if workspace != "" && snap.Workspace != workspace {
+			continue
+		}
+		if !from.IsZero() && snap.Taken.Before(from) {
+			continue
+		}
+		if !to.IsZero() && snap.Taken.After(to) {
+			continue
+		}
+		snaps = append(snaps, snap)
+	}
+
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].Taken.Before(snaps[j].Taken)
+	})
+
+	return snaps, nil
+}
+
+func NewSnapshotter(zc *zenhub.ZenHubClient, repoID int, workspace string, store *FileStore, interval time.Duration) (*Snapshotter, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("Snapshot interval must be > 0, not %s",
+			interval)
+	}
+	return &Snapshotter{
+		ZenHubClient: zc,
+		RepoID:       repoID,
+		Workspace:    workspace,
+		Store:        store,
+		Interval:     interval,
+	}, nil
+}
+
+// Take fetches the board and saves a snapshot of it
+func (s *Snapshotter) Take() (*Snapshot, error) {
+	return s.take(s.ZenHubClient)
+}
+
+func (s *Snapshotter) take(zc *zenhub.ZenHubClient) (*Snapshot, error) {
+	board, err := zc.GetBoard(s.RepoID, s.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := TakeSnapshot(board)
+	if err = s.Store.Save(snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Run takes a snapshot right away and then every Interval until ctx is
+// done. Errors are logged, they don't stop the loop.
+func (s *Snapshotter) Run(ctx context.Context) error {
+	if s.Interval <= 0 {
+		return fmt.Errorf("Snapshot interval must be > 0, not %s", s.Interval)
+	}
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	zc := s.WithContext(ctx)
+	for {
+		if _, err := s.take(zc); err != nil && ctx.Err() == nil {
+			log.Printf("Error taking snapshot: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// BuildCFD returns the number of issues in each pipeline for each
+// snapshot. Pipelines are in board order, based on the latest snapshot.
+func BuildCFD(snaps []*Snapshot) *CFD {
+	cfd := &CFD{
+		Pipelines: []string{},
+		Points:    []*CFDPoint{},
+	}
+
+	seen := map[string]bool{}
+	for i := len(snaps) - 1; i >= 0; i-- {
+		for _, p := range snaps[i].Pipelines {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				cfd.Pipelines = append(cfd.Pipelines, p.Name)
+			}
+		}
+	}
+
+	for _, snap := range snaps {
+		point := &CFDPoint{
+			Time:   snap.Taken,
+			Counts: map[string]int{},
+		}
+		for _, p := range snap.Pipelines {
+			point.Counts[p.Name] = len(p.Issues)
+		}
+		cfd.Points = append(cfd.Points, point)
+	}
+
+	return cfd
+}
+
+// CurrentWIP returns the number of issues, and their total estimate, in
+// each pipeline of the snapshot
+func CurrentWIP(snap *Snapshot) []*WIP {
+	wips := []*WIP{}
+	for _, p := range snap.Pipelines {
+		wips = append(wips, &WIP{
+			Pipeline: p.Name,
+			Issues:   len(p.Issues),
+			Estimate: p.Estimate,
+		})
+	}
+	return wips
+}
+
+// Throughput counts the issues that showed up in the 'done' pipeline in
+// each 'period', starting at the first snapshot
+func Throughput(snaps []*Snapshot, done string, period time.Duration) []*ThroughputPoint {
+	points := []*ThroughputPoint{}
+	if len(snaps) == 0 || period <= 0 {
+		return points
+	}
+
+	start := snaps[0].Taken
+	wasDone := map[int]bool{}
+	if p := snaps[0].Pipeline(done); p != nil {
+		for _, num := range p.Issues {
+			wasDone[num] = true
+		}
+	}
+
+	for _, snap := range snaps[1:] {
+		bucket := int(snap.Taken.Sub(start) / period)
+		for len(points) <= bucket {
+			points = append(points, &ThroughputPoint{
+				Start: start.Add(time.Duration(len(points)) * period),
+			})
+		}
+
+		isDone := map[int]bool{}
+		if p := snap.Pipeline(done); p != nil {
+			for _, num := range p.Issues {
+				isDone[num] = true
+				if !wasDone[num] {
+					points[bucket].Issues++
+				}
+			}
+		}
+		wasDone = isDone
+	}
+
+	return points
+}
+
+// BoardCycleTimes computes the cycle times from 'start' to 'end' of all
+// issues on the board using their ZenHub event history
+func BoardCycleTimes(board *zenhub.Board, start string, end string) (*CycleTimes, error) {
+	timelines, err := board.GetTimelines()
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*zenhub.IssueTimeline{}
+	for _, tl := range timelines {
+		list = append(list, tl)
+	}
+	return ComputeCycleTimes(list, start, end), nil
+}
+
+func ComputeCycleTimes(timelines []*zenhub.IssueTimeline, start string, end string) *CycleTimes {
+	ct := &CycleTimes{
+		Start:   start,
+		End:     end,
+		Samples: []time.Duration{},
+	}
+
+	for _, tl := range timelines {
+		if d, ok := tl.CycleTime(start, end); ok {
+			ct.Samples = append(ct.Samples, d)
+		}
+	}
+	sort.Slice(ct.Samples, func(i, j int) bool {
+		return ct.Samples[i] < ct.Samples[j]
+	})
+
+	ct.Count = len(ct.Samples)
+	ct.P50 = percentile(ct.Samples, 50)
+	ct.P85 = percentile(ct.Samples, 85)
+	ct.P95 = percentile(ct.Samples, 95)
+
+	return ct
+}
+
+// percentile uses the nearest-rank method, 'sorted' must be sorted
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Durations are written as hours in JSON and CSV
+func hours(d time.Duration) string {
+	return strconv.FormatFloat(d.Hours(), 'f', 2, 64)
+}
+
+func (ct *CycleTimes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Start string  `json:"start"`
+		End   string  `json:"end"`
+		Count int     `json:"count"`
+		P50   float64 `json:"p50_hours"`
+		P85   float64 `json:"p85_hours"`
+		P95   float64 `json:"p95_hours"`
+	}{ct.Start, ct.End, ct.Count, ct.P50.Hours(), ct.P85.Hours(), ct.P95.Hours()})
+}
+
+func WriteJSON(w io.Writer, report interface{}) error {
+	buf, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, '\n'))
+	return err
+}
+
+func (cfd *CFD) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"time"}, cfd.Pipelines...))
+	for _, point := range cfd.Points {
+		row := []string{point.Time.UTC().Format(time.RFC3339)}
+		for _, p := range cfd.Pipelines {
+			row = append(row, strconv.Itoa(point.Counts[p]))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func WriteWIPCSV(w io.Writer, wips []*WIP) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"pipeline", "issues", "estimate"})
+	for _, wip := range wips {
+		cw.Write([]string{wip.Pipeline, strconv.Itoa(wip.Issues),
+			strconv.Itoa(wip.Estimate)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func WriteThroughputCSV(w io.Writer, points []*ThroughputPoint) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"start", "issues"})
+	for _, point := range points {
+		cw.Write([]string{point.Start.UTC().Format(time.RFC3339),
+			strconv.Itoa(point.Issues)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (ct *CycleTimes) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"start", "end", "count", "p50_hours", "p85_hours",
+		"p95_hours"})
+	cw.Write([]string{ct.Start, ct.End, strconv.Itoa(ct.Count),
+		hours(ct.P50), hours(ct.P85), hours(ct.P95)})
+	cw.Flush()
+	return cw.Error()
+}
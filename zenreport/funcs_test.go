@@ -0,0 +1,254 @@
+package zenreport
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/duglin/integration/zenhub"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func snapshot(at time.Duration, pipelines ...*PipelineSnapshot) *Snapshot {
+	return &Snapshot{
+		Taken:     t0.Add(at),
+		RepoID:    1,
+		Workspace: "Team",
+		Pipelines: pipelines,
+	}
+}
+
+func pipeline(name string, issues ...int) *PipelineSnapshot {
+	return &PipelineSnapshot{Name: name, Issues: issues}
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	issue := func(num, estimate int) *zenhub.PipelineIssue {
+		pi := &zenhub.PipelineIssue{Issue_Number: num}
+		pi.Estimate.Value = estimate
+		return pi
+	}
+	board := &zenhub.Board{
+		Workspace: &zenhub.Workspace{Name: "Team"},
+		RepoID:    1,
+		Pipelines: []*zenhub.Pipeline{
+			{Name: "Backlog", Issues: []*zenhub.PipelineIssue{issue(1, 3), issue(2, 5)}},
+			{Name: "Done"},
+		},
+	}
+
+	snap := TakeSnapshot(board)
+	if snap.Workspace != "Team" || snap.RepoID != 1 || len(snap.Pipelines) != 2 {
+		t.Fatalf("bad snapshot: %+v", snap)
+	}
+	backlog := snap.Pipeline("Backlog")
+	if !reflect.DeepEqual(backlog.Issues, []int{1, 2}) || backlog.Estimate != 8 {
+		t.Errorf("bad Backlog: %+v", backlog)
+	}
+	if done := snap.Pipeline("Done"); done == nil || len(done.Issues) != 0 {
+		t.Errorf("bad Done: %+v", done)
+	}
+	if snap.Pipeline("Missing") != nil {
+		t.Errorf("found a missing pipeline")
+	}
+}
+
+func TestBuildCFD(t *testing.T) {
+	snaps := []*Snapshot{
+		snapshot(0, pipeline("Backlog", 1, 2, 3), pipeline("Old", 4)),
+		snapshot(time.Hour, pipeline("Backlog", 2, 3), pipeline("Doing", 1)),
+		snapshot(2*time.Hour, pipeline("Backlog", 3), pipeline("Doing", 2),
+			pipeline("Done", 1)),
+	}
+
+	cfd := BuildCFD(snaps)
+
+	// Latest board order first, then pipelines that were removed
+	want := []string{"Backlog", "Doing", "Done", "Old"}
+	if !reflect.DeepEqual(cfd.Pipelines, want) {
+		t.Errorf("got pipelines %q, want %q", cfd.Pipelines, want)
+	}
+
+	counts := []map[string]int{
+		{"Backlog": 3, "Old": 1},
+		{"Backlog": 2, "Doing": 1},
+		{"Backlog": 1, "Doing": 1, "Done": 1},
+	}
+	for i, point := range cfd.Points {
+		if !point.Time.Equal(snaps[i].Taken) ||
+			!reflect.DeepEqual(point.Counts, counts[i]) {
+			t.Errorf("point %d: got %v %v, want %v", i, point.Time,
+				point.Counts, counts[i])
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := cfd.WriteCSV(buf); err != nil {
+		t.Fatal(err)
+	}
+	csv := "time,Backlog,Doing,Done,Old\n" +
+		"2024-01-01T00:00:00Z,3,0,0,1\n" +
+		"2024-01-01T01:00:00Z,2,1,0,0\n" +
+		"2024-01-01T02:00:00Z,1,1,1,0\n"
+	if buf.String() != csv {
+		t.Errorf("got CSV:\n%s\nwant:\n%s", buf, csv)
+	}
+
+	if cfd = BuildCFD(nil); len(cfd.Pipelines) != 0 || len(cfd.Points) != 0 {
+		t.Errorf("empty CFD: %+v", cfd)
+	}
+}
+
+func TestCurrentWIP(t *testing.T) {
+	doing := pipeline("Doing", 1, 2)
+	doing.Estimate = 8
+	snap := snapshot(0, pipeline("Backlog"), doing)
+
+	want := []*WIP{
+		{Pipeline: "Backlog", Issues: 0, Estimate: 0},
+		{Pipeline: "Doing", Issues: 2, Estimate: 8},
+	}
+	if got := CurrentWIP(snap); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestThroughput(t *testing.T) {
+	day := 24 * time.Hour
+	snaps := []*Snapshot{
+		snapshot(0, pipeline("Done", 1)),
+		snapshot(12*time.Hour, pipeline("Done", 1, 2)),
+		snapshot(20*time.Hour, pipeline("Done", 1, 2, 3)),
+		// Reopened and closed again counts again
+		snapshot(30*time.Hour, pipeline("Done", 1)),
+		snapshot(70*time.Hour, pipeline("Done", 1, 2, 4)),
+	}
+
+	got := []int{}
+	for i, point := range Throughput(snaps, "Done", day) {
+		if !point.Start.Equal(t0.Add(time.Duration(i) * day)) {
+			t.Errorf("point %d starts at %s", i, point.Start)
+		}
+		got = append(got, point.Issues)
+	}
+	if want := []int{2, 0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if points := Throughput(snaps, "Done", 0); len(points) != 0 {
+		t.Errorf("zero period: %+v", points)
+	}
+	if points := Throughput(nil, "Done", day); len(points) != 0 {
+		t.Errorf("no snapshots: %+v", points)
+	}
+}
+
+func TestComputeCycleTimes(t *testing.T) {
+	timeline := func(hours ...int) *zenhub.IssueTimeline {
+		names := []string{"Backlog", "In Progress", "Review", "Done"}
+		tl := &zenhub.IssueTimeline{}
+		for i, h := range hours {
+			tl.Stints = append(tl.Stints, &zenhub.PipelineStint{
+				Pipeline: names[i],
+				Entered:  t0.Add(time.Duration(h) * time.Hour),
+			})
+		}
+		return tl
+	}
+
+	timelines := []*zenhub.IssueTimeline{}
+	// In Progress -> Done of 1..10 hours, in a random order
+	for _, h := range []int{5, 1, 9, 3, 7, 2, 10, 4, 8, 6} {
+		timelines = append(timelines, timeline(0, 10, 11, 10+h))
+	}
+	// Not done yet, or never started
+	timelines = append(timelines, timeline(0, 10), &zenhub.IssueTimeline{})
+
+	ct := ComputeCycleTimes(timelines, "In Progress", "Done")
+	if ct.Count != 10 || len(ct.Samples) != 10 {
+		t.Fatalf("got %d samples, want 10", ct.Count)
+	}
+	for i, d := range ct.Samples {
+		if d != time.Duration(i+1)*time.Hour {
+			t.Errorf("sample %d: got %s", i, d)
+		}
+	}
+	if ct.P50 != 5*time.Hour || ct.P85 != 9*time.Hour || ct.P95 != 10*time.Hour {
+		t.Errorf("got p50=%s p85=%s p95=%s", ct.P50, ct.P85, ct.P95)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := ct.WriteCSV(buf); err != nil {
+		t.Fatal(err)
+	}
+	csv := "start,end,count,p50_hours,p85_hours,p95_hours\n" +
+		"In Progress,Done,10,5.00,9.00,10.00\n"
+	if buf.String() != csv {
+		t.Errorf("got CSV:\n%s\nwant:\n%s", buf, csv)
+	}
+
+	ct = ComputeCycleTimes(nil, "In Progress", "Done")
+	if ct.Count != 0 || ct.P50 != 0 || ct.P95 != 0 {
+		t.Errorf("no samples: %+v", ct)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{1, 2, 3, 4}
+	tests := map[float64]time.Duration{0: 1, 25: 1, 26: 2, 50: 2, 75: 3, 76: 4, 100: 4}
+	for p, want := range tests {
+		if got := percentile(sorted, p); got != want {
+			t.Errorf("p%v: got %d, want %d", p, got, want)
+		}
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	fs, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snaps := []*Snapshot{
+		snapshot(2*time.Hour, pipeline("Backlog", 1)),
+		snapshot(0, pipeline("Backlog", 1, 2)),
+		snapshot(0, pipeline("Backlog", 3)), // same time, must not overwrite
+		snapshot(time.Hour, pipeline("Backlog")),
+	}
+	other := snapshot(time.Hour, pipeline("Backlog", 9))
+	other.Workspace = "Other Team"
+	snaps = append(snaps, other)
+
+	for _, snap := range snaps {
+		if err = fs.Save(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := fs.Load(1, "Team", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 {
+		t.Fatalf("got %d snapshots, want 4", len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Taken.Before(all[i-1].Taken) {
+			t.Errorf("snapshots aren't sorted")
+		}
+	}
+
+	some, err := fs.Load(1, "", t0.Add(time.Hour), t0.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(some) != 2 {
+		t.Errorf("got %d snapshots in range, want 2", len(some))
+	}
+
+	if none, _ := fs.Load(2, "", time.Time{}, time.Time{}); len(none) != 0 {
+		t.Errorf("got snapshots of another repo")
+	}
+}
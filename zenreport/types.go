@@ -0,0 +1,74 @@
+// Package zenreport takes periodic snapshots of ZenHub boards and turns
+// them into cumulative flow, WIP, throughput and cycle time reports.
+package zenreport
+
+import (
+	"time"
+
+	"github.com/duglin/integration/zenhub"
+)
+
+type PipelineSnapshot struct {
+	Name     string `json:"name"`
+	Issues   []int  `json:"issues"`
+	Estimate int    `json:"estimate"` // total of the issues' estimates
+}
+
+// Snapshot is the state of a Board at one point in time
+type Snapshot struct {
+	Taken     time.Time           `json:"taken"`
+	Workspace string              `json:"workspace"`
+	RepoID    int                 `json:"repo_id"`
+	Pipelines []*PipelineSnapshot `json:"pipelines"` // in board order
+}
+
+// FileStore saves each Snapshot as a JSON file in Dir
+type FileStore struct {
+	Dir string
+}
+
+// Snapshotter takes a snapshot of a board every Interval and saves it
+type Snapshotter struct {
+	*zenhub.ZenHubClient
+
+	RepoID    int
+	Workspace string
+	Store     *FileStore
+	Interval  time.Duration
+}
+
+// CFDPoint is one point of a cumulative flow diagram: the number of
+// issues in each pipeline at time Time
+type CFDPoint struct {
+	Time   time.Time      `json:"time"`
+	Counts map[string]int `json:"counts"`
+}
+
+type CFD struct {
+	Pipelines []string    `json:"pipelines"` // in board order
+	Points    []*CFDPoint `json:"points"`
+}
+
+type WIP struct {
+	Pipeline string `json:"pipeline"`
+	Issues   int    `json:"issues"`
+	Estimate int    `json:"estimate"`
+}
+
+// ThroughputPoint is the number of issues that reached the done pipeline
+// during [Start, Start+period)
+type ThroughputPoint struct {
+	Start  time.Time `json:"start"`
+	Issues int       `json:"issues"`
+}
+
+// CycleTimes are written to JSON with the percentiles in hours
+type CycleTimes struct {
+	Start   string // pipeline names
+	End     string
+	Count   int
+	Samples []time.Duration // sorted
+	P50     time.Duration
+	P85     time.Duration
+	P95     time.Duration
+}